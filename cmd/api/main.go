@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"geo-notifications/internal/auth"
 	"geo-notifications/internal/config"
+	"geo-notifications/internal/grpcapi"
 	"geo-notifications/internal/handler"
+	"geo-notifications/internal/metrics"
+	pb "geo-notifications/internal/pb/geonotifications/v1"
 	"geo-notifications/internal/repository"
 	"geo-notifications/internal/service"
+	"geo-notifications/internal/tracing"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,16 +20,30 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
 	logger := logrus.New()
+	// JSON, чтобы сборщик логов разбирал поля (request_id, route, status)
+	logger.SetFormatter(&logrus.JSONFormatter{})
 
 	dbURL := config.GetDBURL()
 	redisCfg := config.GetRedisConfig()
 	if dbURL == "" {
 		logger.Fatal("DATABASE_URL is empty")
 	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		if err := runMigrate(ctx, logger, dbURL, os.Args[2:]); err != nil {
+			logger.WithError(err).Fatal("migrate failed")
+		}
+		return
+	}
 	if redisCfg.Addr == "" {
 		logger.Fatal("REDIS_ADDR is empty")
 	}
@@ -45,32 +65,103 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// трассировка; без OTLP endpoint спаны не пишутся
+	shutdownTracing, err := tracing.Setup(ctx, config.IsTracingEnabled())
+	if err != nil {
+		logger.WithError(err).Fatal("failed to set up tracing")
+	}
+
 	// init storage (Postgres + Redis)
 	storage, err := repository.NewStorage(dbURL, redisCfg)
 	if err != nil {
 		logger.WithError(err).Fatal("failed to initialize storage")
 	}
 
-	if err := storage.CreateTables(ctx); err != nil {
-		logger.WithError(err).Fatal("failed to create tables")
+	// реплики стартуют одновременно — миграции применит первая, остальные
+	// дождутся её на advisory lock
+	applied, err := storage.Migrate(ctx)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to migrate database")
+	}
+	for _, m := range applied {
+		logger.WithField("migration", m.String()).Info("migration applied")
+	}
+
+	svcCfg := service.Config{
+		AlertsSecret:    config.GetAlertsTokenSecret(),
+		BootstrapAPIKey: config.GetBootstrapAPIKey(),
+	}
+	if jwtCfg := config.GetJWTConfig(); jwtCfg.JWKS != "" {
+		defaultRole := auth.RoleReporter
+		if jwtCfg.DefaultRole != "" {
+			r, ok := auth.ParseRole(jwtCfg.DefaultRole)
+			if !ok {
+				logger.Fatalf("JWT_DEFAULT_ROLE %q is not a valid role", jwtCfg.DefaultRole)
+			}
+			defaultRole = r
+		}
+		svcCfg.JWT = auth.NewJWTVerifier(auth.JWTConfig{
+			JWKS:        jwtCfg.JWKS,
+			Issuer:      jwtCfg.Issuer,
+			Audience:    jwtCfg.Audience,
+			RoleClaim:   jwtCfg.RoleClaim,
+			DefaultRole: defaultRole,
+			TenantClaim: jwtCfg.TenantClaim,
+		})
+		logger.Infof("JWT bearer tokens are validated against %s", jwtCfg.JWKS)
+	}
+	rateCfg := config.GetRateLimitConfig()
+	if svcCfg.RateLimits.Default, err = service.ParseRateLimit(rateCfg.Default); err != nil {
+		logger.WithError(err).Fatal("invalid RATE_LIMIT_DEFAULT")
+	}
+	if svcCfg.RateLimits.Routes, err = service.ParseRateLimitRoutes(rateCfg.Routes); err != nil {
+		logger.WithError(err).Fatal("invalid RATE_LIMIT_ROUTES")
+	}
+	if svcCfg.RateLimits.User, err = service.ParseRateLimit(rateCfg.User); err != nil {
+		logger.WithError(err).Fatal("invalid RATE_LIMIT_USER")
+	}
+	if svcCfg.IdempotencyTTL, err = config.GetIdempotencyTTL(); err != nil {
+		logger.WithError(err).Fatal("invalid IDEMPOTENCY_TTL")
+	}
+	if svcCfg.HeatmapMinUsers, err = config.GetHeatmapMinUsers(); err != nil {
+		logger.WithError(err).Fatal("invalid HEATMAP_MIN_USERS")
+	}
+	shutdownDelay, err := config.GetShutdownDelay()
+	if err != nil {
+		logger.WithError(err).Fatal("invalid SHUTDOWN_DELAY")
+	}
+	// общее с воркером состояние для /readyz
+	svcCfg.Lifecycle = service.NewLifecycle()
+	if svcCfg.AlertsSecret == "" {
+		logger.Warn("ALERTS_TOKEN_SECRET is empty, websocket alerts are disabled")
+	}
+	authDisabled := config.IsAuthDisabled()
+	if authDisabled {
+		logger.Warn("AUTH_DISABLED=true, API keys are not checked")
 	}
 
 	// init service
-	incidentService := service.NewIncidentService(storage, logger)
+	incidentService := service.NewIncidentService(storage, logger, svcCfg)
+
+	// раздача событий SSE-подписчикам этой реплики
+	go incidentService.RunEvents(ctx)
 
 	// init handler
 	h := handler.NewHandler(logger, incidentService, statsMinutes)
+	h.SetAlertsOrigins(config.GetAlertsAllowedOrigins())
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/v1/incidents", h.IncidentsHandler)
-	mux.HandleFunc("/api/v1/incidents/", h.IncidentByIDHandler)
-	mux.HandleFunc("/api/v1/location/check", h.LocationHandler)
-	mux.HandleFunc("/api/v1/incidents/stats", h.IncidentsStatsHandler)
-	mux.HandleFunc("/api/v1/system/health", h.HealthHandler)
+	// лимиты и ключи идемпотентности считаются по клиенту, поэтому стоят
+	// после аутентификации
+	var root http.Handler = h.RateLimitMiddleware(h.IdempotencyMiddleware(handler.NewRouter(h)))
+	if !authDisabled {
+		root = h.AuthMiddleware(root)
+	}
+	// access-лог внутри span запроса, чтобы в строку попал trace_id
+	root = h.TracingMiddleware(h.RequestLogMiddleware(h.MetricsMiddleware(root)))
 
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: root,
 	}
 
 	go func() {
@@ -81,14 +172,76 @@ func main() {
 
 	logger.Info("Server started on :8080")
 
+	// метрики Prometheus на отдельном порту
+	metrics.Registry.MustRegister(storage.Collector())
+	metricsAddr := config.GetMetricsAddr()
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+	metricsServer := &http.Server{
+		Addr:    metricsAddr,
+		Handler: metricsMux,
+	}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.WithError(err).Fatal("metrics server ListenAndServe error")
+		}
+	}()
+	logger.Infof("Metrics server started on %s", metricsAddr)
+
+	// gRPC API поверх того же сервисного слоя
+	grpcAddr := config.GetGRPCAddr()
+	grpcListener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		logger.WithError(err).Fatal("failed to listen for gRPC")
+	}
+	var (
+		unaryInterceptors  []grpc.UnaryServerInterceptor
+		streamInterceptors []grpc.StreamServerInterceptor
+	)
+	if !authDisabled {
+		authenticator := grpcapi.NewAuthenticator(incidentService)
+		unaryInterceptors = append(unaryInterceptors, authenticator.UnaryInterceptor)
+		streamInterceptors = append(streamInterceptors, authenticator.StreamInterceptor)
+	}
+	rateLimiter := grpcapi.NewRateLimiter(logger, incidentService)
+	unaryInterceptors = append(unaryInterceptors, rateLimiter.UnaryInterceptor)
+	streamInterceptors = append(streamInterceptors, rateLimiter.StreamInterceptor)
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	pb.RegisterIncidentServiceServer(grpcServer, grpcapi.NewServer(logger, incidentService, statsMinutes))
+	reflection.Register(grpcServer)
+
+	go func() {
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.WithError(err).Fatal("gRPC server Serve error")
+		}
+	}()
+
+	logger.Infof("gRPC server started on %s", grpcAddr)
+
 	// webhook worker
-	worker := service.NewWebhookWorker(storage, logger, webhookURL)
+	worker := service.NewWebhookWorker(storage, logger, webhookURL, svcCfg.Lifecycle)
 	go worker.Run(ctx)
 
+	// автоматическая очистка корзины
+	trashRetention, err := config.GetTrashRetention()
+	if err != nil {
+		logger.WithError(err).Fatal("invalid TRASH_RETENTION")
+	}
+	go service.NewTrashPurger(storage, logger, trashRetention).Run(ctx)
+
 	// ждём сигнал
 	<-ctx.Done()
 	logger.Info("Shutdown signal received")
 
+	// /readyz уже отвечает 503, но сервер ещё принимает запросы, пока
+	// балансировщик не заметит это и не уберёт под
+	svcCfg.Lifecycle.StartShutdown()
+	time.Sleep(shutdownDelay)
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -97,6 +250,26 @@ func main() {
 	} else {
 		logger.Info("Server stopped gracefully")
 	}
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.WithError(err).Warn("metrics server forced to shutdown")
+	}
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+		logger.Info("gRPC server stopped gracefully")
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+		logger.Warn("gRPC server forced to stop")
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.WithError(err).Warn("failed to flush traces")
+	}
 
 	if err := storage.Close(); err != nil {
 		logger.WithError(err).Warn("storage close error")
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"geo-notifications/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = `usage: main migrate [up | down [N] | status]
+  up        применить все новые миграции (по умолчанию)
+  down [N]  откатить N последних миграций (по умолчанию 1)
+  status    показать применённые и ожидающие миграции`
+
+// runMigrate — подкоманда migrate: работает только с Postgres, Redis и
+// остальные переменные окружения ей не нужны.
+func runMigrate(ctx context.Context, logger *logrus.Logger, dbURL string, args []string) error {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	repo, err := repository.NewPostgresRepo(dbURL)
+	if err != nil {
+		return fmt.Errorf("connect to postgres: %w", err)
+	}
+	defer repo.Close()
+
+	switch {
+	case cmd == "up" && len(args) == 0:
+		applied, err := repo.MigrateUp(ctx)
+		for _, m := range applied {
+			logger.WithField("migration", m.String()).Info("migration applied")
+		}
+		if err == nil && len(applied) == 0 {
+			logger.Info("schema is up to date")
+		}
+		return err
+
+	case cmd == "down" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			if steps, err = strconv.Atoi(args[0]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q\n%s", args[0], migrateUsage)
+			}
+		}
+		reverted, err := repo.MigrateDown(ctx, steps)
+		for _, m := range reverted {
+			logger.WithField("migration", m.String()).Info("migration reverted")
+		}
+		if err == nil && len(reverted) == 0 {
+			logger.Info("no migrations to revert")
+		}
+		return err
+
+	case cmd == "status" && len(args) == 0:
+		statuses, err := repo.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if st.Unknown {
+				applied += " (unknown to this binary)"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown migrate command %q\n%s", cmd, migrateUsage)
+}
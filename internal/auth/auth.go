@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+type Role string
+
+const (
+	RoleAdmin      Role = "admin"
+	RoleDispatcher Role = "dispatcher"
+	RoleReporter   Role = "reporter"
+	RoleViewer     Role = "viewer"
+)
+
+type Permission string
+
+const (
+	PermIncidentsRead  Permission = "incidents:read"
+	PermIncidentsWrite Permission = "incidents:write"
+	// удаление инцидентов насовсем
+	PermIncidentsPurge Permission = "incidents:purge"
+	PermLocationCheck  Permission = "location:check"
+	PermStatsRead      Permission = "stats:read"
+	PermEventsRead     Permission = "events:read"
+	PermAPIKeysManage  Permission = "api_keys:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermIncidentsRead, PermIncidentsWrite, PermIncidentsPurge, PermLocationCheck,
+		PermStatsRead, PermEventsRead, PermAPIKeysManage,
+	},
+	RoleDispatcher: {PermIncidentsRead, PermIncidentsWrite, PermStatsRead, PermEventsRead},
+	RoleReporter:   {PermLocationCheck},
+	RoleViewer:     {PermIncidentsRead, PermStatsRead, PermEventsRead},
+}
+
+func ParseRole(s string) (Role, bool) {
+	r := Role(s)
+	_, ok := rolePermissions[r]
+	return r, ok
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, perm := range rolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTenant — тенант однотенантной установки; его получают клиенты,
+// для которых тенант не указан.
+const DefaultTenant = "default"
+
+// тенант попадает в ключи Redis, поэтому допускаем только безопасные символы
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func ValidTenant(s string) bool {
+	return tenantPattern.MatchString(s)
+}
+
+// Principal — аутентифицированный клиент API: API-ключ (KeyID) или
+// пользователь с JWT (Subject). Все данные клиента ограничены его Tenant.
+type Principal struct {
+	KeyID   int64
+	Name    string
+	Role    Role
+	Subject string
+	Tenant  string
+	// AllTenants — bootstrap-ключ: управляет API-ключами всех тенантов
+	AllTenants bool
+}
+
+// SubjectUserID возвращает user_id из subject JWT. ok=false, если клиент
+// пришёл не с JWT; err — если subject не является user_id.
+func (p *Principal) SubjectUserID() (userID int64, ok bool, err error) {
+	if p == nil || p.Subject == "" {
+		return 0, false, nil
+	}
+	userID, err = strconv.ParseInt(p.Subject, 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, true, fmt.Errorf("token subject %q is not a user id", p.Subject)
+	}
+	return userID, true, nil
+}
+
+// ClientID — стабильный идентификатор клиента для лимитов запросов.
+func (p *Principal) ClientID() string {
+	switch {
+	case p == nil:
+		return ""
+	case p.KeyID > 0:
+		return "key:" + strconv.FormatInt(p.KeyID, 10)
+	case p.Subject != "":
+		return "jwt:" + p.Tenant + ":" + p.Subject
+	default:
+		return "key:" + p.Name
+	}
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext возвращает клиента запроса или nil, если
+// аутентификация отключена.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// TenantFromContext возвращает тенант клиента запроса; без аутентификации —
+// DefaultTenant.
+func TenantFromContext(ctx context.Context) string {
+	if p := PrincipalFromContext(ctx); p != nil && p.Tenant != "" {
+		return p.Tenant
+	}
+	return DefaultTenant
+}
+
+const (
+	apiKeyPrefix = "gn_"
+	// сколько символов ключа храним открыто, чтобы его можно было узнать в списке
+	APIKeyDisplayLen = len(apiKeyPrefix) + 8
+)
+
+// GenerateAPIKey создаёт новый ключ. В базе хранится только его хэш.
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
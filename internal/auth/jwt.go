@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	ErrInvalidJWT = errors.New("invalid token")
+	ErrExpiredJWT = errors.New("token expired")
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// allowedAlgs — единственные алгоритмы подписи, которые принимает JWTVerifier.
+var allowedAlgs = []jose.SignatureAlgorithm{jose.RS256, jose.ES256}
+
+const (
+	defaultJWKSCacheTTL = 10 * time.Minute
+	// не чаще, чем раз в minJWKSRefresh, перечитываем JWKS из-за неизвестного kid
+	minJWKSRefresh = time.Minute
+	clockSkew      = 30 * time.Second
+)
+
+type JWTConfig struct {
+	// JWKS — URL (http/https) или путь к файлу с набором ключей
+	JWKS     string
+	Issuer   string
+	Audience string
+	// RoleClaim — claim с ролью; без него клиент получает DefaultRole
+	RoleClaim   string
+	DefaultRole Role
+	// TenantClaim — claim с тенантом; без него клиент получает DefaultTenant
+	TenantClaim string
+	CacheTTL    time.Duration
+}
+
+// Claims — проверенные поля токена.
+type Claims struct {
+	Subject   string
+	Issuer    string
+	ExpiresAt time.Time
+	Raw       map[string]json.RawMessage
+}
+
+// JWTVerifier проверяет RS256/ES256 токены по ключам из JWKS. Ключи
+// кэшируются на CacheTTL; токен с неизвестным kid вызывает внеочередное
+// перечитывание набора, чтобы подхватить ротацию ключей.
+type JWTVerifier struct {
+	cfg    JWTConfig
+	client *http.Client
+	now    func() time.Time
+
+	// fetches склеивает одновременные запросы JWKS в один
+	fetches singleflight.Group
+
+	// mu защищает только сам набор ключей и отметки времени
+	mu          sync.RWMutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastRefresh time.Time
+	// lastErr — ошибка последнего запроса JWKS; пока ключей нет, её
+	// получают все токены до конца окна minJWKSRefresh
+	lastErr error
+}
+
+func NewJWTVerifier(cfg JWTConfig) *JWTVerifier {
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = defaultJWKSCacheTTL
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "role"
+	}
+	if cfg.DefaultRole == "" {
+		cfg.DefaultRole = RoleReporter
+	}
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = "tenant"
+	}
+	return &JWTVerifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 5 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// Verify проверяет подпись и стандартные claims и возвращает Principal.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	claims, err := v.VerifyClaims(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	role := v.cfg.DefaultRole
+	if raw, ok := claims.Raw[v.cfg.RoleClaim]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("%w: claim %s must be a string", ErrInvalidJWT, v.cfg.RoleClaim)
+		}
+		r, ok := ParseRole(s)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidJWT, s)
+		}
+		role = r
+	}
+
+	tenant := DefaultTenant
+	if raw, ok := claims.Raw[v.cfg.TenantClaim]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil || !ValidTenant(s) {
+			return nil, fmt.Errorf("%w: invalid claim %s", ErrInvalidJWT, v.cfg.TenantClaim)
+		}
+		tenant = s
+	}
+
+	return &Principal{Name: claims.Subject, Subject: claims.Subject, Role: role, Tenant: tenant}, nil
+}
+
+func (v *JWTVerifier) VerifyClaims(ctx context.Context, token string) (*Claims, error) {
+	// разбор отклоняет любой alg вне allowedAlgs, в том числе none и HS*
+	jws, err := jose.ParseSignedCompact(token, allowedAlgs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+
+	key, err := v.key(ctx, jws.Signatures[0].Header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := jws.Verify(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidJWT)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidJWT, err)
+	}
+	return v.validateClaims(raw)
+}
+
+func (v *JWTVerifier) validateClaims(raw map[string]json.RawMessage) (*Claims, error) {
+	var std struct {
+		Sub string          `json:"sub"`
+		Iss string          `json:"iss"`
+		Aud json.RawMessage `json:"aud"`
+		Exp *float64        `json:"exp"`
+		Nbf *float64        `json:"nbf"`
+	}
+	data, _ := json.Marshal(raw)
+	if err := json.Unmarshal(data, &std); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidJWT, err)
+	}
+
+	now := v.now()
+	if std.Exp == nil {
+		return nil, fmt.Errorf("%w: exp is required", ErrInvalidJWT)
+	}
+	exp := time.Unix(int64(*std.Exp), 0)
+	if now.After(exp.Add(clockSkew)) {
+		return nil, ErrExpiredJWT
+	}
+	if std.Nbf != nil && now.Add(clockSkew).Before(time.Unix(int64(*std.Nbf), 0)) {
+		return nil, fmt.Errorf("%w: token not yet valid", ErrInvalidJWT)
+	}
+	if std.Sub == "" {
+		return nil, fmt.Errorf("%w: sub is required", ErrInvalidJWT)
+	}
+	if v.cfg.Issuer != "" && std.Iss != v.cfg.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidJWT, std.Iss)
+	}
+	if v.cfg.Audience != "" && !audienceContains(std.Aud, v.cfg.Audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidJWT)
+	}
+
+	return &Claims{Subject: std.Sub, Issuer: std.Iss, ExpiresAt: exp, Raw: raw}, nil
+}
+
+func audienceContains(raw json.RawMessage, want string) bool {
+	if len(raw) == 0 {
+		return false
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == want
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, aud := range list {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key возвращает ключ по kid, при необходимости перечитывая JWKS. Сам
+// запрос JWKS идёт без блокировки: пока он висит, токены с известными
+// ключами проверяются по закэшированному набору.
+func (v *JWTVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.RLock()
+	keys := v.keys
+	stale := v.now().Sub(v.fetchedAt) > v.cfg.CacheTTL
+	v.mu.RUnlock()
+
+	refreshed := false
+	if keys == nil || stale {
+		fresh, err := v.refresh(ctx)
+		if err == nil {
+			keys = fresh
+		} else if keys == nil {
+			// при ошибке продолжаем работать со старым набором, если он есть
+			return nil, err
+		}
+		refreshed = true
+	}
+
+	if key, ok := lookupKey(keys, kid); ok {
+		return key, nil
+	}
+
+	// возможно, ключи ротировали — перечитываем (refresh сам не даёт делать
+	// это чаще minJWKSRefresh)
+	if !refreshed {
+		fresh, err := v.refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := lookupKey(fresh, kid); ok {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func lookupKey(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid != "" {
+		key, ok := keys[kid]
+		return key, ok
+	}
+	// без kid допустим только набор из одного ключа
+	if len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// refresh перечитывает JWKS, но не чаще minJWKSRefresh — в этом окне
+// возвращает текущий набор, а если его нет, ошибку последнего запроса:
+// недоступный JWKS не должен дёргаться на каждый токен. Одновременные
+// вызовы ждут один общий запрос; ожидающий может уйти по своему ctx, не
+// обрывая запрос для остальных.
+func (v *JWTVerifier) refresh(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	ch := v.fetches.DoChan("jwks", func() (any, error) {
+		now := v.now()
+		v.mu.Lock()
+		if !v.lastRefresh.IsZero() && now.Sub(v.lastRefresh) < minJWKSRefresh {
+			keys, err := v.keys, v.lastErr
+			v.mu.Unlock()
+			if keys == nil {
+				return nil, err
+			}
+			return keys, nil
+		}
+		v.lastRefresh = now
+		v.mu.Unlock()
+
+		keys, err := v.fetchJWKS(context.WithoutCancel(ctx))
+
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.lastErr = err
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+		v.fetchedAt = now
+		return keys, nil
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(map[string]crypto.PublicKey), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (v *JWTVerifier) fetchJWKS(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	data, err := v.readJWKS(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load jwks: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	return keys, nil
+}
+
+func (v *JWTVerifier) readJWKS(ctx context.Context) ([]byte, error) {
+	src := v.cfg.JWKS
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.ReadFile(src)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// ParseJWKS разбирает JWK Set, оставляя RSA и EC P-256 ключи для подписи.
+// Ключи других типов пропускаются, а не ломают весь набор.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i, data := range set.Keys {
+		var head struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+		}
+		if err := json.Unmarshal(data, &head); err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if head.Kty != "RSA" && (head.Kty != "EC" || head.Crv != "P-256") {
+			continue
+		}
+
+		var k jose.JSONWebKey
+		if err := k.UnmarshalJSON(data); err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if !k.IsPublic() {
+			return nil, fmt.Errorf("key %d: not a public key", i)
+		}
+		kid := k.KeyID
+		if kid == "" {
+			kid = strconv.Itoa(i)
+		}
+		keys[kid] = k.Key
+	}
+	return keys, nil
+}
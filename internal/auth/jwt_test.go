@@ -0,0 +1,399 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testSigner struct {
+	kid string
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newRSASigner(t *testing.T, kid string) testSigner {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigner{kid: kid, rsa: key}
+}
+
+func newECSigner(t *testing.T, kid string) testSigner {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testSigner{kid: kid, ec: key}
+}
+
+func (s testSigner) jwk(t *testing.T) map[string]string {
+	t.Helper()
+	enc := base64.RawURLEncoding.EncodeToString
+	if s.rsa != nil {
+		return map[string]string{
+			"kty": "RSA", "kid": s.kid, "use": "sig",
+			"n": enc(s.rsa.N.Bytes()),
+			"e": enc(big.NewInt(int64(s.rsa.E)).Bytes()),
+		}
+	}
+	pub, err := s.ec.PublicKey.ECDH()
+	if err != nil {
+		t.Fatal(err)
+	}
+	point := pub.Bytes()
+	return map[string]string{
+		"kty": "EC", "kid": s.kid, "crv": "P-256",
+		"x": enc(point[1:33]),
+		"y": enc(point[33:]),
+	}
+}
+
+func (s testSigner) sign(t *testing.T, alg string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": s.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	enc := base64.RawURLEncoding.EncodeToString
+	signingInput := enc(header) + "." + enc(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	if s.rsa != nil {
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, s.rsa, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		der, err := ecdsa.SignASN1(rand.Reader, s.ec, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rs struct{ R, S *big.Int }
+		if _, err := asn1.Unmarshal(der, &rs); err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		rs.R.FillBytes(sig[:32])
+		rs.S.FillBytes(sig[32:])
+	}
+	return signingInput + "." + enc(sig)
+}
+
+func writeJWKS(t *testing.T, signers ...testSigner) string {
+	t.Helper()
+	keys := make([]map[string]string, 0, len(signers))
+	for _, s := range signers {
+		keys = append(keys, s.jwk(t))
+	}
+	data, _ := json.Marshal(map[string]any{"keys": keys})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub": "42",
+		"iss": "https://id.example.com",
+		"aud": []string{"geo-notifications"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	rsaSigner := newRSASigner(t, "rsa-1")
+	ecSigner := newECSigner(t, "ec-1")
+	other := newRSASigner(t, "rsa-1")
+
+	v := NewJWTVerifier(JWTConfig{
+		JWKS:     writeJWKS(t, rsaSigner, ecSigner),
+		Issuer:   "https://id.example.com",
+		Audience: "geo-notifications",
+	})
+
+	with := func(key string, value any) map[string]any {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantErr  error
+		wantRole Role
+	}{
+		{"rs256", rsaSigner.sign(t, "RS256", validClaims()), nil, RoleReporter},
+		{"es256", ecSigner.sign(t, "ES256", validClaims()), nil, RoleReporter},
+		{"role claim", rsaSigner.sign(t, "RS256", with("role", "dispatcher")), nil, RoleDispatcher},
+		{"unknown role", rsaSigner.sign(t, "RS256", with("role", "root")), ErrInvalidJWT, ""},
+		{"audience string", rsaSigner.sign(t, "RS256", with("aud", "geo-notifications")), nil, RoleReporter},
+		{"expired", rsaSigner.sign(t, "RS256", with("exp", time.Now().Add(-time.Hour).Unix())), ErrExpiredJWT, ""},
+		{"no exp", rsaSigner.sign(t, "RS256", with("exp", nil)), ErrInvalidJWT, ""},
+		{"not yet valid", rsaSigner.sign(t, "RS256", with("nbf", time.Now().Add(time.Hour).Unix())), ErrInvalidJWT, ""},
+		{"no sub", rsaSigner.sign(t, "RS256", with("sub", nil)), ErrInvalidJWT, ""},
+		{"wrong issuer", rsaSigner.sign(t, "RS256", with("iss", "https://evil.example.com")), ErrInvalidJWT, ""},
+		{"wrong audience", rsaSigner.sign(t, "RS256", with("aud", "other")), ErrInvalidJWT, ""},
+		{"bad signature", other.sign(t, "RS256", validClaims()), ErrInvalidJWT, ""},
+		{"alg mismatch", rsaSigner.sign(t, "ES256", validClaims()), ErrInvalidJWT, ""},
+		{"malformed", "not-a-jwt", ErrInvalidJWT, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Verify(context.Background(), tt.token)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Subject != "42" || p.Role != tt.wantRole {
+				t.Fatalf("unexpected principal: %+v", p)
+			}
+		})
+	}
+}
+
+func TestJWTVerifier_Tenant(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	v := NewJWTVerifier(JWTConfig{JWKS: writeJWKS(t, signer)})
+	ctx := context.Background()
+
+	p, err := v.Verify(ctx, signer.sign(t, "RS256", validClaims()))
+	if err != nil || p.Tenant != DefaultTenant {
+		t.Fatalf("expected default tenant, got %+v, %v", p, err)
+	}
+
+	claims := validClaims()
+	claims["tenant"] = "kazan"
+	p, err = v.Verify(ctx, signer.sign(t, "RS256", claims))
+	if err != nil || p.Tenant != "kazan" {
+		t.Fatalf("expected tenant kazan, got %+v, %v", p, err)
+	}
+
+	claims["tenant"] = "Kazan:1"
+	if _, err := v.Verify(ctx, signer.sign(t, "RS256", claims)); !errors.Is(err, ErrInvalidJWT) {
+		t.Fatalf("expected ErrInvalidJWT for invalid tenant, got %v", err)
+	}
+}
+
+func TestJWTVerifier_RejectsUnsignedAlgs(t *testing.T) {
+	signer := newRSASigner(t, "rsa-1")
+	v := NewJWTVerifier(JWTConfig{JWKS: writeJWKS(t, signer)})
+
+	enc := base64.RawURLEncoding.EncodeToString
+	payload, _ := json.Marshal(validClaims())
+	for _, alg := range []string{"none", "HS256"} {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "rsa-1"})
+		token := enc(header) + "." + enc(payload) + "."
+		if _, err := v.Verify(context.Background(), token); !errors.Is(err, ErrInvalidJWT) {
+			t.Fatalf("alg %s: expected ErrInvalidJWT, got %v", alg, err)
+		}
+	}
+}
+
+func TestJWTVerifier_KeyRotation(t *testing.T) {
+	oldKey := newRSASigner(t, "old")
+	newKey := newECSigner(t, "new")
+
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		signers := []testSigner{oldKey}
+		if rotated.Load() {
+			signers = []testSigner{newKey}
+		}
+		keys := make([]map[string]string, 0, len(signers))
+		for _, s := range signers {
+			keys = append(keys, s.jwk(t))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	v := NewJWTVerifier(JWTConfig{JWKS: srv.URL})
+	v.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	if _, err := v.Verify(ctx, oldKey.sign(t, "RS256", validClaims())); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	rotated.Store(true)
+	newToken := newKey.sign(t, "ES256", validClaims())
+
+	// сразу после загрузки внеочередное перечитывание ещё запрещено
+	if _, err := v.Verify(ctx, newToken); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey before refresh interval, got %v", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected 1 fetch, got %d", got)
+	}
+
+	now = now.Add(minJWKSRefresh)
+	if _, err := v.Verify(ctx, newToken); err != nil {
+		t.Fatalf("new key after rotation: %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("expected 2 fetches, got %d", got)
+	}
+}
+
+func TestJWTVerifier_JWKSUnavailable(t *testing.T) {
+	signer := newRSASigner(t, "main")
+
+	var down atomic.Bool
+	down.Store(true)
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{signer.jwk(t)}})
+	}))
+	defer srv.Close()
+
+	now := time.Now()
+	v := NewJWTVerifier(JWTConfig{JWKS: srv.URL})
+	v.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	token := signer.sign(t, "RS256", validClaims())
+	_, first := v.Verify(ctx, token)
+	if first == nil {
+		t.Fatal("expected error while JWKS is unavailable")
+	}
+
+	// до конца окна JWKS не перечитывается, токены получают ту же ошибку
+	down.Store(false)
+	if _, err := v.Verify(ctx, token); err == nil || err.Error() != first.Error() {
+		t.Fatalf("expected %v within refresh interval, got %v", first, err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Fatalf("expected 1 fetch, got %d", got)
+	}
+
+	now = now.Add(minJWKSRefresh)
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("after refresh interval: %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("expected 2 fetches, got %d", got)
+	}
+}
+
+func TestJWTVerifier_SlowJWKSRefresh(t *testing.T) {
+	known := newRSASigner(t, "known")
+	rotated := newECSigner(t, "rotated")
+
+	var fetches atomic.Int32
+	var slow atomic.Bool
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		keys := []map[string]string{known.jwk(t)}
+		if slow.Load() {
+			<-release
+			keys = append(keys, rotated.jwk(t))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer srv.Close()
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+
+	start := time.Now()
+	v := NewJWTVerifier(JWTConfig{JWKS: srv.URL})
+	v.now = func() time.Time { return start }
+
+	ctx := context.Background()
+	knownToken := known.sign(t, "RS256", validClaims())
+	if _, err := v.Verify(ctx, knownToken); err != nil {
+		t.Fatalf("initial verify: %v", err)
+	}
+
+	v.now = func() time.Time { return start.Add(minJWKSRefresh) }
+	slow.Store(true)
+
+	// токены с новым kid ждут одного общего запроса JWKS
+	const waiters = 8
+	rotatedToken := rotated.sign(t, "ES256", validClaims())
+	errs := make(chan error, waiters)
+	for range waiters {
+		go func() {
+			_, err := v.Verify(ctx, rotatedToken)
+			errs <- err
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for fetches.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("refresh did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// пока запрос висит, токен с известным ключом проверяется без ожидания
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(ctx, knownToken)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("known key during refresh: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("known key blocked by JWKS refresh")
+	}
+
+	close(release)
+	for range waiters {
+		if err := <-errs; err != nil {
+			t.Fatalf("rotated key: %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Fatalf("expected 2 fetches, got %d", got)
+	}
+}
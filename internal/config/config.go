@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -27,3 +30,150 @@ func GetRedisConfig() RedisConfig {
 	}
 	return redisConfig
 }
+
+func GetAlertsTokenSecret() string {
+	return os.Getenv("ALERTS_TOKEN_SECRET")
+}
+
+// GetAlertsAllowedOrigins — origin'ы веб-клиентов через запятую, которым,
+// помимо страниц самого API, разрешено подключаться к WebSocket-алертам
+// (ALERTS_ALLOWED_ORIGINS, например "https://m.example.com").
+func GetAlertsAllowedOrigins() []string {
+	var origins []string
+	for _, o := range strings.Split(os.Getenv("ALERTS_ALLOWED_ORIGINS"), ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+func GetGRPCAddr() string {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = ":9091"
+	}
+	return addr
+}
+
+// GetMetricsAddr — адрес, где отдаются метрики Prometheus на /metrics
+// (METRICS_ADDR, по умолчанию :9102). Отдельный порт — чтобы метрики не
+// проходили через ключи и лимиты API и не торчали наружу вместе с ним.
+func GetMetricsAddr() string {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":9102"
+	}
+	return addr
+}
+
+// IsTracingEnabled — экспортировать ли спаны OpenTelemetry: да, если задан
+// OTEL_EXPORTER_OTLP_ENDPOINT или OTEL_EXPORTER_OTLP_TRACES_ENDPOINT.
+func IsTracingEnabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+func GetBootstrapAPIKey() string {
+	return os.Getenv("ADMIN_API_KEY")
+}
+
+// IsAuthDisabled отключает проверку API-ключей (только для локальной разработки).
+func IsAuthDisabled() bool {
+	return os.Getenv("AUTH_DISABLED") == "true"
+}
+
+type JWTConfig struct {
+	JWKS        string `env:"JWT_JWKS"`
+	Issuer      string `env:"JWT_ISSUER"`
+	Audience    string `env:"JWT_AUDIENCE"`
+	RoleClaim   string `env:"JWT_ROLE_CLAIM"`
+	DefaultRole string `env:"JWT_DEFAULT_ROLE"`
+	TenantClaim string `env:"JWT_TENANT_CLAIM"`
+}
+
+// GetJWTConfig читает настройки проверки JWT; пустой JWKS — JWT выключен.
+func GetJWTConfig() JWTConfig {
+	return JWTConfig{
+		JWKS:        os.Getenv("JWT_JWKS"),
+		Issuer:      os.Getenv("JWT_ISSUER"),
+		Audience:    os.Getenv("JWT_AUDIENCE"),
+		RoleClaim:   os.Getenv("JWT_ROLE_CLAIM"),
+		DefaultRole: os.Getenv("JWT_DEFAULT_ROLE"),
+		TenantClaim: os.Getenv("JWT_TENANT_CLAIM"),
+	}
+}
+
+// GetIdempotencyTTL — сколько хранится ответ на запрос с Idempotency-Key
+// (IDEMPOTENCY_TTL, например "24h"); 0 — значение по умолчанию.
+func GetIdempotencyTTL() (time.Duration, error) {
+	v := os.Getenv("IDEMPOTENCY_TTL")
+	if v == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// GetTrashRetention — сколько деактивированный инцидент лежит в корзине до
+// автоматического удаления (TRASH_RETENTION, по умолчанию 720h); "0" или
+// "off" выключают удаление.
+func GetTrashRetention() (time.Duration, error) {
+	v := os.Getenv("TRASH_RETENTION")
+	switch v {
+	case "":
+		return 30 * 24 * time.Hour, nil
+	case "off":
+		return 0, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// GetShutdownDelay — сколько после сигнала остановки /readyz отвечает 503
+// до закрытия HTTP-сервера, чтобы балансировщик успел вывести под из
+// ротации (SHUTDOWN_DELAY, по умолчанию 5s; "0" — без паузы).
+func GetShutdownDelay() (time.Duration, error) {
+	v := os.Getenv("SHUTDOWN_DELAY")
+	if v == "" {
+		return 5 * time.Second, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// GetHeatmapMinUsers — сколько разных пользователей должно быть в ячейке
+// тепловой карты, чтобы её показать (HEATMAP_MIN_USERS, по умолчанию 5).
+func GetHeatmapMinUsers() (int, error) {
+	v := os.Getenv("HEATMAP_MIN_USERS")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err == nil && n < 1 {
+		err = fmt.Errorf("must be positive, got %d", n)
+	}
+	return n, err
+}
+
+// RateLimitConfig — лимиты запросов в формате "<count>/<s|m|h>", "off" —
+// без ограничения.
+type RateLimitConfig struct {
+	// для маршрутов без своего лимита, по умолчанию выключен
+	Default string `env:"RATE_LIMIT_DEFAULT"`
+	// "<path>=<limit>,..." — лимиты клиента по маршрутам
+	Routes string `env:"RATE_LIMIT_ROUTES"`
+	// проверки локации одного user_id
+	User string `env:"RATE_LIMIT_USER"`
+}
+
+func GetRateLimitConfig() RateLimitConfig {
+	cfg := RateLimitConfig{
+		Default: os.Getenv("RATE_LIMIT_DEFAULT"),
+		Routes:  os.Getenv("RATE_LIMIT_ROUTES"),
+		User:    os.Getenv("RATE_LIMIT_USER"),
+	}
+	if cfg.Routes == "" {
+		cfg.Routes = "/api/v1/location/check=50/s"
+	}
+	if cfg.User == "" {
+		cfg.User = "30/m"
+	}
+	return cfg
+}
@@ -0,0 +1,134 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"geo-notifications/internal/auth"
+	pb "geo-notifications/internal/pb/geonotifications/v1"
+	"geo-notifications/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodPerms — права на методы IncidentService, как у HTTP-маршрутов.
+// Пустое значение — публичный метод.
+var methodPerms = map[string]auth.Permission{
+	pb.IncidentService_CreateIncident_FullMethodName:     auth.PermIncidentsWrite,
+	pb.IncidentService_GetIncident_FullMethodName:        auth.PermIncidentsRead,
+	pb.IncidentService_ListIncidents_FullMethodName:      auth.PermIncidentsRead,
+	pb.IncidentService_UpdateIncident_FullMethodName:     auth.PermIncidentsWrite,
+	pb.IncidentService_PatchIncident_FullMethodName:      auth.PermIncidentsWrite,
+	pb.IncidentService_DeactivateIncident_FullMethodName: auth.PermIncidentsWrite,
+	pb.IncidentService_CheckLocation_FullMethodName:      auth.PermLocationCheck,
+	pb.IncidentService_GetStats_FullMethodName:           auth.PermStatsRead,
+	pb.IncidentService_Health_FullMethodName:             "",
+	pb.IncidentService_WatchMatches_FullMethodName:       auth.PermEventsRead,
+}
+
+// Authenticator проверяет JWT (authorization: Bearer <token>) или API-ключ
+// из метаданных x-api-key (authorization: ApiKey <key>) так же, как HTTP
+// AuthMiddleware.
+type Authenticator struct {
+	service service.IncidentService
+}
+
+func NewAuthenticator(svc service.IncidentService) *Authenticator {
+	return &Authenticator{service: svc}
+}
+
+func (a *Authenticator) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	perm, known := methodPerms[method]
+	if !known {
+		// сторонние сервисы (reflection) не защищаем, а незарегистрированный
+		// метод IncidentService — ошибка конфигурации
+		if strings.HasPrefix(method, "/"+pb.IncidentService_ServiceDesc.ServiceName+"/") {
+			return nil, status.Error(codes.PermissionDenied, "method has no access policy")
+		}
+		return ctx, nil
+	}
+	if perm == "" {
+		return ctx, nil
+	}
+
+	var (
+		principal *auth.Principal
+		err       error
+	)
+	if token := bearerFromMetadata(ctx); token != "" {
+		principal, err = a.service.AuthenticateBearerToken(ctx, token)
+	} else if key := apiKeyFromMetadata(ctx); key != "" {
+		principal, err = a.service.AuthenticateAPIKey(ctx, key)
+	} else {
+		return nil, status.Error(codes.Unauthenticated, "API key or bearer token is required")
+	}
+	if err != nil {
+		var svcErr *service.Error
+		if errors.As(err, &svcErr) {
+			return nil, status.Error(codes.Unauthenticated, svcErr.Error())
+		}
+		return nil, status.Error(codes.Internal, "server error")
+	}
+	if !principal.Role.Can(perm) {
+		return nil, status.Errorf(codes.PermissionDenied, "role %s is not allowed to %s", principal.Role, perm)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func apiKeyFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get("x-api-key"); len(v) > 0 && v[0] != "" {
+		return v[0]
+	}
+	if v := md.Get("authorization"); len(v) > 0 {
+		if scheme, key, ok := strings.Cut(v[0], " "); ok && strings.EqualFold(scheme, "ApiKey") {
+			return strings.TrimSpace(key)
+		}
+	}
+	return ""
+}
+
+func bearerFromMetadata(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get("authorization"); len(v) > 0 {
+		if scheme, token, ok := strings.Cut(v[0], " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,79 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+
+	"geo-notifications/internal/auth"
+	pb "geo-notifications/internal/pb/geonotifications/v1"
+	"geo-notifications/internal/service"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+)
+
+// methodRoutes — HTTP-маршрут, лимит которого действует на метод: лимиты
+// (и бакеты клиента) у обоих API общие.
+var methodRoutes = map[string]string{
+	pb.IncidentService_CreateIncident_FullMethodName:     "/api/v1/incidents",
+	pb.IncidentService_GetIncident_FullMethodName:        "/api/v1/incidents/{id}",
+	pb.IncidentService_ListIncidents_FullMethodName:      "/api/v1/incidents",
+	pb.IncidentService_UpdateIncident_FullMethodName:     "/api/v1/incidents/{id}",
+	pb.IncidentService_PatchIncident_FullMethodName:      "/api/v1/incidents/{id}",
+	pb.IncidentService_DeactivateIncident_FullMethodName: "/api/v1/incidents/{id}",
+	pb.IncidentService_CheckLocation_FullMethodName:      "/api/v1/location/check",
+	pb.IncidentService_GetStats_FullMethodName:           "/api/v1/incidents/stats",
+	pb.IncidentService_Health_FullMethodName:             "/api/v1/system/health",
+	pb.IncidentService_WatchMatches_FullMethodName:       "/api/v1/events",
+}
+
+// RateLimiter ограничивает вызовы клиента так же, как HTTP
+// RateLimitMiddleware. Ставится после Authenticator.
+type RateLimiter struct {
+	logger  *logrus.Logger
+	service service.IncidentService
+}
+
+func NewRateLimiter(logger *logrus.Logger, svc service.IncidentService) *RateLimiter {
+	return &RateLimiter{logger: logger, service: svc}
+}
+
+func (l *RateLimiter) UnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := l.allow(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *RateLimiter) StreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := l.allow(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (l *RateLimiter) allow(ctx context.Context, method string) error {
+	route, ok := methodRoutes[method]
+	if !ok {
+		return nil
+	}
+	if _, err := l.service.AllowRequest(ctx, route, rateLimitClient(ctx)); err != nil {
+		return toStatus(l.logger, err, "failed to check rate limit")
+	}
+	return nil
+}
+
+func rateLimitClient(ctx context.Context) string {
+	if id := auth.PrincipalFromContext(ctx).ClientID(); id != "" {
+		return id
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return "ip:" + host
+	}
+	return "ip:unknown"
+}
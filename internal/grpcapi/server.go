@@ -0,0 +1,323 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+	pb "geo-notifications/internal/pb/geonotifications/v1"
+	"geo-notifications/internal/service"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Server struct {
+	pb.UnimplementedIncidentServiceServer
+
+	logger             *logrus.Logger
+	service            service.IncidentService
+	statsWindowMinutes int
+}
+
+func NewServer(logger *logrus.Logger, svc service.IncidentService, statsWindowMinutes int) *Server {
+	return &Server{
+		logger:             logger,
+		service:            svc,
+		statsWindowMinutes: statsWindowMinutes,
+	}
+}
+
+func (s *Server) CreateIncident(ctx context.Context, req *pb.CreateIncidentRequest) (*pb.CreateIncidentResponse, error) {
+	incident := model.Incident{
+		Title:       req.GetTitle(),
+		Description: req.GetDescription(),
+		Latitude:    req.GetLatitude(),
+		Longitude:   req.GetLongitude(),
+		RadiusM:     int(req.GetRadiusM()),
+		Polygon:     fromPBPolygon(req.GetPolygon()),
+	}
+	if err := s.service.CreateIncident(ctx, &incident); err != nil {
+		return nil, toStatus(s.logger, err, "error in service CreateIncident call")
+	}
+	return &pb.CreateIncidentResponse{Incident: toPBIncident(&incident)}, nil
+}
+
+func (s *Server) GetIncident(ctx context.Context, req *pb.GetIncidentRequest) (*pb.GetIncidentResponse, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid incident id")
+	}
+	incident, err := s.service.GetIncidentByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(s.logger, err, "error getting incident by id")
+	}
+	return &pb.GetIncidentResponse{Incident: toPBIncident(incident)}, nil
+}
+
+func (s *Server) ListIncidents(ctx context.Context, req *pb.ListIncidentsRequest) (*pb.ListIncidentsResponse, error) {
+	page := int(req.GetPage())
+	if page == 0 {
+		page = 1
+	}
+	pageSize := int(req.GetPageSize())
+	if pageSize == 0 {
+		pageSize = 20
+	}
+	if page < 1 || pageSize < 1 {
+		return nil, status.Error(codes.InvalidArgument, "invalid pagination parameters")
+	}
+
+	items, err := s.service.GetItemsList(ctx, page, pageSize)
+	if err != nil {
+		return nil, toStatus(s.logger, err, "error while getting list of incidents")
+	}
+
+	resp := &pb.ListIncidentsResponse{
+		Items:    make([]*pb.Incident, 0, len(items)),
+		Page:     int32(page),
+		PageSize: int32(pageSize),
+	}
+	for i := range items {
+		resp.Items = append(resp.Items, toPBIncident(&items[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) UpdateIncident(ctx context.Context, req *pb.UpdateIncidentRequest) (*pb.UpdateIncidentResponse, error) {
+	in := req.GetIncident()
+	if in.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid incident id")
+	}
+	if req.GetExpectedVersion() <= 0 {
+		return nil, status.Error(codes.FailedPrecondition, "expected_version is required")
+	}
+	incident := model.Incident{
+		ID:          in.GetId(),
+		Title:       in.GetTitle(),
+		Description: in.GetDescription(),
+		Latitude:    in.GetLatitude(),
+		Longitude:   in.GetLongitude(),
+		RadiusM:     int(in.GetRadiusM()),
+		Active:      in.GetActive(),
+		Polygon:     fromPBPolygon(in.GetPolygon()),
+	}
+	updated, err := s.service.UpdateIncident(ctx, &incident, req.GetExpectedVersion())
+	if err != nil {
+		return nil, toStatus(s.logger, err, "error updating incident")
+	}
+	return &pb.UpdateIncidentResponse{Incident: toPBIncident(updated)}, nil
+}
+
+func (s *Server) PatchIncident(ctx context.Context, req *pb.PatchIncidentRequest) (*pb.PatchIncidentResponse, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid incident id")
+	}
+	patch := model.IncidentPatch{
+		Title:       req.Title,
+		Description: req.Description,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		Active:      req.Active,
+	}
+	if req.RadiusM != nil {
+		radius := int(req.GetRadiusM())
+		patch.RadiusM = &radius
+	}
+	switch {
+	case req.GetClearPolygon() && req.GetPolygon() != nil:
+		return nil, status.Error(codes.InvalidArgument, "polygon and clear_polygon are mutually exclusive")
+	case req.GetClearPolygon():
+		patch.Polygon = new(model.Polygon)
+	case req.GetPolygon() != nil:
+		polygon := fromPBPolygon(req.GetPolygon())
+		patch.Polygon = &polygon
+	}
+	incident, err := s.service.PatchIncident(ctx, req.GetId(), &patch, req.GetExpectedVersion())
+	if err != nil {
+		return nil, toStatus(s.logger, err, "error patching incident")
+	}
+	return &pb.PatchIncidentResponse{Incident: toPBIncident(incident)}, nil
+}
+
+func (s *Server) DeactivateIncident(ctx context.Context, req *pb.DeactivateIncidentRequest) (*pb.DeactivateIncidentResponse, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid incident id")
+	}
+	if err := s.service.DeactivateIncident(ctx, req.GetId()); err != nil {
+		return nil, toStatus(s.logger, err, "error deactivating incident")
+	}
+	return &pb.DeactivateIncidentResponse{}, nil
+}
+
+func (s *Server) CheckLocation(ctx context.Context, req *pb.CheckLocationRequest) (*pb.CheckLocationResponse, error) {
+	userID := req.GetUserId()
+	if subjectID, ok, err := auth.PrincipalFromContext(ctx).SubjectUserID(); ok {
+		if err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if userID != 0 && userID != subjectID {
+			return nil, status.Error(codes.PermissionDenied, "user_id does not match token subject")
+		}
+		userID = subjectID
+	}
+
+	locations, err := s.service.CheckLocations(ctx, model.LocationRequest{
+		UserID:    userID,
+		Latitude:  req.GetLatitude(),
+		Longitude: req.GetLongitude(),
+	})
+	if err != nil {
+		return nil, toStatus(s.logger, err, "error while checking location")
+	}
+	return &pb.CheckLocationResponse{
+		UserId:       locations.UserID,
+		Latitude:     locations.Latitude,
+		Longitude:    locations.Longitude,
+		LocationsIds: locations.LocationsIDS,
+	}, nil
+}
+
+func (s *Server) GetStats(ctx context.Context, _ *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+	count, err := s.service.GetUserStats(ctx, s.statsWindowMinutes)
+	if err != nil {
+		return nil, toStatus(s.logger, err, "failed to get incidents stats")
+	}
+	return &pb.GetStatsResponse{UserCount: int32(count)}, nil
+}
+
+func (s *Server) Health(ctx context.Context, _ *pb.HealthRequest) (*pb.HealthResponse, error) {
+	resp := &pb.HealthResponse{Status: "ok", Db: "ok", Redis: "ok"}
+	if err := s.service.HealthCheck(ctx); err != nil {
+		resp.Status = "degraded"
+		if err.DBError != nil {
+			resp.Db = "error"
+		}
+		if err.RedisError != nil {
+			resp.Redis = "error"
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) WatchMatches(req *pb.WatchMatchesRequest, stream pb.IncidentService_WatchMatchesServer) error {
+	events, err := s.service.SubscribeEvents(stream.Context(), req.GetLastEventId())
+	if err != nil {
+		return toStatus(s.logger, err, "failed to subscribe to events")
+	}
+
+	for ev := range events {
+		if ev.Type != model.EventLocationMatched || ev.Match == nil {
+			continue
+		}
+		if req.GetUserId() != 0 && ev.Match.UserID != req.GetUserId() {
+			continue
+		}
+		if err := stream.Send(&pb.WatchMatchesResponse{
+			EventId:      ev.ID,
+			UserId:       ev.Match.UserID,
+			Latitude:     ev.Match.Latitude,
+			Longitude:    ev.Match.Longitude,
+			LocationsIds: ev.Match.LocationsIDS,
+			CheckedAt:    timestamppb.New(ev.Match.CheckedAt),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := stream.Context().Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Unavailable, "event stream closed")
+}
+
+func toStatus(logger *logrus.Logger, err error, msg string) error {
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) {
+		st := status.New(codeForKind(svcErr.Kind), svcErr.Error())
+		if svcErr.RateLimit != nil {
+			// клиенту — когда можно повторить
+			if withRetry, err := st.WithDetails(&errdetails.RetryInfo{
+				RetryDelay: durationpb.New(svcErr.RateLimit.RetryAfter),
+			}); err == nil {
+				st = withRetry
+			}
+		}
+		return st.Err()
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	logger.WithError(err).Error(msg)
+	return status.Error(codes.Internal, "server error")
+}
+
+func codeForKind(kind service.ErrorKind) codes.Code {
+	switch kind {
+	case service.KindValidation:
+		return codes.InvalidArgument
+	case service.KindNotFound:
+		return codes.NotFound
+	case service.KindConflict:
+		return codes.Aborted
+	case service.KindUnauthorized:
+		return codes.Unauthenticated
+	case service.KindForbidden:
+		return codes.PermissionDenied
+	case service.KindRateLimited:
+		return codes.ResourceExhausted
+	case service.KindPrecondition:
+		return codes.FailedPrecondition
+	default:
+		return codes.Internal
+	}
+}
+
+func toPBIncident(in *model.Incident) *pb.Incident {
+	return &pb.Incident{
+		Id:          in.ID,
+		Title:       in.Title,
+		Description: in.Description,
+		Latitude:    in.Latitude,
+		Longitude:   in.Longitude,
+		RadiusM:     int32(in.RadiusM),
+		Active:      in.Active,
+		Version:     in.Version,
+		CreatedAt:   timestamppb.New(in.CreatedAt),
+		UpdatedAt:   timestamppb.New(in.UpdatedAt),
+		Polygon:     toPBPolygon(in.Polygon),
+	}
+}
+
+func toPBPolygon(p model.Polygon) *pb.Polygon {
+	if len(p) == 0 {
+		return nil
+	}
+	rings := make([]*pb.Ring, len(p))
+	for i, ring := range p {
+		points := make([]*pb.Point, len(ring))
+		for j, pt := range ring {
+			points[j] = &pb.Point{Longitude: pt[0], Latitude: pt[1]}
+		}
+		rings[i] = &pb.Ring{Points: points}
+	}
+	return &pb.Polygon{Rings: rings}
+}
+
+func fromPBPolygon(p *pb.Polygon) model.Polygon {
+	if len(p.GetRings()) == 0 {
+		return nil
+	}
+	polygon := make(model.Polygon, len(p.GetRings()))
+	for i, ring := range p.GetRings() {
+		polygon[i] = make([][2]float64, len(ring.GetPoints()))
+		for j, pt := range ring.GetPoints() {
+			polygon[i][j] = [2]float64{pt.GetLongitude(), pt.GetLatitude()}
+		}
+	}
+	return polygon
+}
@@ -0,0 +1,284 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+	pb "geo-notifications/internal/pb/geonotifications/v1"
+	"geo-notifications/internal/service"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeIncidentService реализует только то, что нужно тестам; остальные
+// методы паникуют через nil-интерфейс.
+type fakeIncidentService struct {
+	service.IncidentService
+
+	created *model.Incident
+	updated *model.Incident
+	patch   *model.IncidentPatch
+	events  []model.Event
+	limited bool
+}
+
+func (f *fakeIncidentService) CreateIncident(ctx context.Context, inc *model.Incident) error {
+	inc.ID = 42
+	inc.Active = true
+	f.created = inc
+	return nil
+}
+
+func (f *fakeIncidentService) UpdateIncident(ctx context.Context, in *model.Incident, ifVersion int64) (*model.Incident, error) {
+	f.updated = in
+	out := *in
+	out.Version = ifVersion + 1
+	return &out, nil
+}
+
+func (f *fakeIncidentService) PatchIncident(ctx context.Context, id int64, patch *model.IncidentPatch, ifVersion int64) (*model.Incident, error) {
+	f.patch = patch
+	return &model.Incident{ID: id, Title: "flood", RadiusM: 500, Version: ifVersion + 1}, nil
+}
+
+func (f *fakeIncidentService) GetIncidentByID(ctx context.Context, id int64) (*model.Incident, error) {
+	return nil, service.NewNotFoundError("incident", id)
+}
+
+func (f *fakeIncidentService) SubscribeEvents(ctx context.Context, lastEventID string) (<-chan model.Event, error) {
+	ch := make(chan model.Event, len(f.events))
+	for _, ev := range f.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (f *fakeIncidentService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	if key == "reporter-key" {
+		return &auth.Principal{KeyID: 1, Name: "app", Role: auth.RoleReporter}, nil
+	}
+	return nil, service.ErrInvalidAPIKey
+}
+
+func (f *fakeIncidentService) AuthenticateBearerToken(ctx context.Context, token string) (*auth.Principal, error) {
+	return nil, service.ErrInvalidBearer
+}
+
+func (f *fakeIncidentService) AllowRequest(ctx context.Context, route, client string) (*service.RateLimitStatus, error) {
+	if f.limited {
+		st := &service.RateLimitStatus{Limit: 1, RetryAfter: 3 * time.Second}
+		return st, service.NewRateLimitError(st)
+	}
+	return nil, nil
+}
+
+func (f *fakeIncidentService) HealthCheck(ctx context.Context) *service.HealthError {
+	return nil
+}
+
+func (f *fakeIncidentService) Readiness(ctx context.Context) *model.Readiness {
+	return &model.Readiness{Status: model.ReadinessReady}
+}
+
+func newTestClient(t *testing.T, svc service.IncidentService, opts ...grpc.ServerOption) pb.IncidentServiceClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	pb.RegisterIncidentServiceServer(srv, NewServer(logrus.New(), svc, 5))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewIncidentServiceClient(conn)
+}
+
+func TestCreateIncident(t *testing.T) {
+	svc := &fakeIncidentService{}
+	client := newTestClient(t, svc)
+
+	resp, err := client.CreateIncident(context.Background(), &pb.CreateIncidentRequest{
+		Title:   "flood",
+		RadiusM: 300,
+	})
+	if err != nil {
+		t.Fatalf("CreateIncident failed: %v", err)
+	}
+	if resp.GetIncident().GetId() != 42 || resp.GetIncident().GetTitle() != "flood" {
+		t.Fatalf("unexpected incident: %+v", resp.GetIncident())
+	}
+	if svc.created == nil || svc.created.RadiusM != 300 {
+		t.Fatalf("incident was not passed correctly to service: %+v", svc.created)
+	}
+}
+
+func TestUpdateIncident_Polygon(t *testing.T) {
+	svc := &fakeIncidentService{}
+	client := newTestClient(t, svc)
+	ctx := context.Background()
+
+	square := &pb.Polygon{Rings: []*pb.Ring{{Points: []*pb.Point{
+		{Longitude: 37.6, Latitude: 55.7},
+		{Longitude: 37.7, Latitude: 55.7},
+		{Longitude: 37.7, Latitude: 55.8},
+		{Longitude: 37.6, Latitude: 55.8},
+		{Longitude: 37.6, Latitude: 55.7},
+	}}}}
+	resp, err := client.UpdateIncident(ctx, &pb.UpdateIncidentRequest{
+		Incident:        &pb.Incident{Id: 7, Title: "flood", Active: true, Polygon: square},
+		ExpectedVersion: 3,
+	})
+	if err != nil {
+		t.Fatalf("UpdateIncident failed: %v", err)
+	}
+	want := model.Polygon{{{37.6, 55.7}, {37.7, 55.7}, {37.7, 55.8}, {37.6, 55.8}, {37.6, 55.7}}}
+	if svc.updated == nil || !reflect.DeepEqual(svc.updated.Polygon, want) {
+		t.Fatalf("polygon was not passed to service: %+v", svc.updated)
+	}
+	if got := resp.GetIncident().GetPolygon(); !proto.Equal(got, square) {
+		t.Fatalf("polygon is missing from response: %v", got)
+	}
+
+	// удаление полигона и замена кругом
+	radius := int32(500)
+	resp2, err := client.PatchIncident(ctx, &pb.PatchIncidentRequest{Id: 7, ClearPolygon: true, RadiusM: &radius})
+	if err != nil {
+		t.Fatalf("PatchIncident failed: %v", err)
+	}
+	if svc.patch.Polygon == nil || *svc.patch.Polygon != nil {
+		t.Fatalf("expected patch to remove polygon, got %+v", svc.patch.Polygon)
+	}
+	if resp2.GetIncident().GetPolygon() != nil {
+		t.Fatalf("circle incident must have no polygon: %v", resp2.GetIncident().GetPolygon())
+	}
+
+	_, err = client.PatchIncident(ctx, &pb.PatchIncidentRequest{Id: 7, ClearPolygon: true, Polygon: square})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for polygon with clear_polygon, got %v", err)
+	}
+}
+
+func TestGetIncident_NotFound(t *testing.T) {
+	client := newTestClient(t, &fakeIncidentService{})
+
+	_, err := client.GetIncident(context.Background(), &pb.GetIncidentRequest{Id: 1})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestWatchMatches_FiltersByUser(t *testing.T) {
+	svc := &fakeIncidentService{
+		events: []model.Event{
+			{ID: "1-0", Type: model.EventIncidentCreated, Incident: &model.Incident{ID: 1}},
+			{ID: "1-1", Type: model.EventLocationMatched, Match: &model.WebhookPayload{UserID: 2, LocationsIDS: []int64{1}}},
+			{ID: "1-2", Type: model.EventLocationMatched, Match: &model.WebhookPayload{UserID: 3, LocationsIDS: []int64{1}}},
+		},
+	}
+	client := newTestClient(t, svc)
+
+	stream, err := client.WatchMatches(context.Background(), &pb.WatchMatchesRequest{UserId: 3})
+	if err != nil {
+		t.Fatalf("WatchMatches failed: %v", err)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("failed to receive match: %v", err)
+	}
+	if msg.GetEventId() != "1-2" || msg.GetUserId() != 3 {
+		t.Fatalf("unexpected match: %+v", msg)
+	}
+
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable after stream end, got %v", err)
+	}
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	svc := &fakeIncidentService{}
+	authenticator := NewAuthenticator(svc)
+	client := newTestClient(t, svc,
+		grpc.UnaryInterceptor(authenticator.UnaryInterceptor),
+		grpc.StreamInterceptor(authenticator.StreamInterceptor),
+	)
+	ctx := context.Background()
+
+	if _, err := client.Health(ctx, &pb.HealthRequest{}); err != nil {
+		t.Fatalf("Health must be public, got %v", err)
+	}
+
+	_, err := client.CreateIncident(ctx, &pb.CreateIncidentRequest{Title: "t"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without key, got %v", err)
+	}
+
+	withKey := metadata.AppendToOutgoingContext(ctx, "x-api-key", "reporter-key")
+	_, err = client.CreateIncident(withKey, &pb.CreateIncidentRequest{Title: "t"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for reporter, got %v", err)
+	}
+
+	stream, err := client.WatchMatches(ctx, &pb.WatchMatchesRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated for stream without key, got %v", err)
+	}
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	svc := &fakeIncidentService{}
+	limiter := NewRateLimiter(logrus.New(), svc)
+	client := newTestClient(t, svc,
+		grpc.UnaryInterceptor(limiter.UnaryInterceptor),
+		grpc.StreamInterceptor(limiter.StreamInterceptor),
+	)
+	ctx := context.Background()
+
+	if _, err := client.Health(ctx, &pb.HealthRequest{}); err != nil {
+		t.Fatalf("expected Health to pass, got %v", err)
+	}
+
+	svc.limited = true
+	_, err := client.Health(ctx, &pb.HealthRequest{})
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			retry = ri
+		}
+	}
+	if retry == nil || retry.GetRetryDelay().AsDuration() != 3*time.Second {
+		t.Fatalf("expected RetryInfo with 3s delay, got %v", st.Details())
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+)
+
+func (h *Handler) APIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.CreateAPIKey(w, r)
+	case http.MethodGet:
+		h.ListAPIKeys(w, r)
+	default:
+		h.methodNotAllowed(w, r)
+	}
+}
+
+func (h *Handler) APIKeyByIDHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/v1/api-keys/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid api key id",
+			service.FieldError{Field: "id", Message: "must be a positive integer"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		h.RevokeAPIKey(w, r, id)
+	default:
+		h.methodNotAllowed(w, r)
+	}
+}
+
+// POST /api/v1/api-keys — ключ в открытом виде возвращается только здесь.
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	var req struct {
+		Name   string `json:"name"`
+		Role   string `json:"role"`
+		Tenant string `json:"tenant"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log(r).WithError(err).Info("invalid request body in CreateAPIKey")
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_json", "invalid JSON")
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(r.Context(), req.Name, req.Role, req.Tenant)
+	if err != nil {
+		h.writeError(w, r, err, "error creating api key")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(key)
+}
+
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.ListAPIKeys(r.Context())
+	if err != nil {
+		h.writeError(w, r, err, "error listing api keys")
+		return
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+
+	resp := struct {
+		Items []model.APIKey `json:"items"`
+	}{
+		Items: keys,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DELETE /api/v1/api-keys/{id} (отзыв)
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request, id int64) {
+	if err := h.service.RevokeAPIKey(r.Context(), id); err != nil {
+		h.writeError(w, r, err, "error revoking api key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
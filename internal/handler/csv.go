@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+)
+
+// колонки CSV в порядке экспорта; при импорте читаются только поля,
+// которые задаёт клиент
+var csvColumns = []string{
+	"id", "title", "description", "latitude", "longitude", "radius_m", "polygon",
+	"version", "created_at", "updated_at",
+}
+
+// csvOptions — формат CSV из параметров запроса: columns=поле:заголовок,...
+// переименовывает колонки, delimiter задаёт разделитель (по умолчанию ",").
+type csvOptions struct {
+	// поле модели → заголовок колонки
+	columns   map[string]string
+	delimiter rune
+}
+
+func parseCSVOptions(q url.Values) (csvOptions, error) {
+	opts := csvOptions{columns: make(map[string]string, len(csvColumns)), delimiter: ','}
+	for _, c := range csvColumns {
+		opts.columns[c] = c
+	}
+
+	var fields []service.FieldError
+	if v := q.Get("columns"); v != "" {
+		for _, item := range strings.Split(v, ",") {
+			field, header, ok := strings.Cut(item, ":")
+			field, header = strings.TrimSpace(field), strings.TrimSpace(header)
+			if _, known := opts.columns[field]; !ok || !known || header == "" {
+				fields = append(fields, service.FieldError{Field: "columns", Message: "must be a list of <field>:<header> with known fields"})
+				break
+			}
+			opts.columns[field] = header
+		}
+	}
+	switch v := q.Get("delimiter"); {
+	case v == "":
+	case v == "tab":
+		opts.delimiter = '\t'
+	case utf8.RuneCountInString(v) == 1 && v != `"` && v != "\n" && v != "\r":
+		opts.delimiter, _ = utf8.DecodeRuneInString(v)
+	default:
+		fields = append(fields, service.FieldError{Field: "delimiter", Message: "must be a single character or tab"})
+	}
+	if len(fields) > 0 {
+		return opts, service.NewValidationError(fields...)
+	}
+	return opts, nil
+}
+
+// csvReader читает CSV построчно; первая строка — заголовок.
+type csvReader struct {
+	r *csv.Reader
+	// поле модели → номер колонки, -1 если её нет
+	index map[string]int
+}
+
+func newCSVReader(r io.Reader, opts csvOptions) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = opts.delimiter
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, invalidFile("invalid_csv", "CSV header row is required")
+	}
+	if err != nil {
+		return nil, readerError(err, "invalid_csv", "invalid CSV")
+	}
+	// Excel пишет BOM в начало файла
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	byHeader := make(map[string]int, len(header))
+	for i, h := range header {
+		byHeader[strings.TrimSpace(h)] = i
+	}
+	index := make(map[string]int, len(opts.columns))
+	for field, h := range opts.columns {
+		index[field] = -1
+		if i, ok := byHeader[h]; ok {
+			index[field] = i
+		}
+	}
+
+	var missing []service.FieldError
+	need := []string{"title"}
+	if index["polygon"] < 0 {
+		need = append(need, "latitude", "longitude", "radius_m")
+	}
+	for _, field := range need {
+		if index[field] < 0 {
+			missing = append(missing, service.FieldError{Field: opts.columns[field], Message: "column is required"})
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &service.Error{
+			Kind:    service.KindValidation,
+			Code:    "invalid_csv",
+			Message: "CSV header misses required columns",
+			Fields:  missing,
+		}
+	}
+
+	cr.ReuseRecord = true
+	return &csvReader{r: cr, index: index}, nil
+}
+
+func (cr *csvReader) Next() (*model.Incident, []service.FieldError, error) {
+	record, err := cr.r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, io.EOF
+	}
+	if errors.Is(err, csv.ErrFieldCount) {
+		return nil, []service.FieldError{{Field: "columns", Message: "wrong number of columns"}}, nil
+	}
+	if err != nil {
+		return nil, nil, readerError(err, "invalid_csv", "invalid CSV")
+	}
+
+	get := func(field string) string {
+		if i := cr.index[field]; i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var in model.Incident
+	var errs []service.FieldError
+	fail := func(field, message string) {
+		errs = append(errs, service.FieldError{Field: field, Message: message})
+	}
+
+	in.Title = csvUnescapeText(get("title"))
+	in.Description = csvUnescapeText(get("description"))
+
+	if v := get("polygon"); v != "" {
+		if err := json.Unmarshal([]byte(v), &in.Polygon); err != nil || len(in.Polygon) == 0 {
+			fail("polygon", "must be GeoJSON polygon coordinates")
+		}
+		return &in, errs, nil
+	}
+
+	in.Latitude = parseCSVFloat(get("latitude"), "latitude", fail)
+	in.Longitude = parseCSVFloat(get("longitude"), "longitude", fail)
+	if !validLonLat(in.Longitude, in.Latitude) {
+		fail("latitude", "coordinates are out of range")
+	}
+	if v := get("radius_m"); v == "" {
+		fail("radius_m", "is required")
+	} else if in.RadiusM, err = strconv.Atoi(v); err != nil {
+		fail("radius_m", "must be an integer")
+	}
+	return &in, errs, nil
+}
+
+// parseCSVFloat принимает и десятичную запятую, как пишет русский Excel.
+func parseCSVFloat(v, field string, fail func(field, message string)) float64 {
+	if v == "" {
+		fail(field, "is required")
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+	if err != nil {
+		fail(field, "must be a number")
+	}
+	return f
+}
+
+// csvEncoder пишет заголовок и по строке на инцидент.
+type csvEncoder struct {
+	w    *csv.Writer
+	opts csvOptions
+}
+
+func newCSVEncoder(w io.Writer, opts csvOptions) *csvEncoder {
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.delimiter
+	return &csvEncoder{w: cw, opts: opts}
+}
+
+func (e *csvEncoder) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (e *csvEncoder) Begin() error {
+	header := make([]string, len(csvColumns))
+	for i, c := range csvColumns {
+		header[i] = e.opts.columns[c]
+	}
+	return e.w.Write(header)
+}
+
+func (e *csvEncoder) Encode(in *model.Incident) error {
+	var polygon string
+	if len(in.Polygon) > 0 {
+		data, err := json.Marshal(in.Polygon)
+		if err != nil {
+			return err
+		}
+		polygon = string(data)
+	}
+	return e.w.Write([]string{
+		strconv.FormatInt(in.ID, 10),
+		csvEscapeText(in.Title),
+		csvEscapeText(in.Description),
+		strconv.FormatFloat(in.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(in.Longitude, 'f', -1, 64),
+		strconv.Itoa(in.RadiusM),
+		polygon,
+		strconv.FormatInt(in.Version, 10),
+		in.CreatedAt.Format(time.RFC3339),
+		in.UpdatedAt.Format(time.RFC3339),
+	})
+}
+
+// csvFormulaPrefixes — с чего начинаются формулы в Excel и LibreOffice.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvEscapeText защищает от CSV-инъекции: текст, который табличный
+// редактор принял бы за формулу, получает префикс "'" и открывается как
+// строка. Импорт снимает этот префикс обратно.
+func csvEscapeText(s string) string {
+	if s != "" && strings.IndexByte(csvFormulaPrefixes, s[0]) >= 0 {
+		return "'" + s
+	}
+	return s
+}
+
+func csvUnescapeText(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.IndexByte(csvFormulaPrefixes, s[1]) >= 0 {
+		return s[1:]
+	}
+	return s
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"geo-notifications/internal/service"
+)
+
+// problem — тело ошибки в формате RFC 7807 (application/problem+json).
+// Code — стабильный машиночитаемый код, Errors — ошибки по полям.
+type problem struct {
+	Type     string               `json:"type"`
+	Title    string               `json:"title"`
+	Status   int                  `json:"status"`
+	Detail   string               `json:"detail,omitempty"`
+	Instance string               `json:"instance,omitempty"`
+	Code     string               `json:"code"`
+	Errors   []service.FieldError `json:"errors,omitempty"`
+}
+
+func (h *Handler) writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, fields ...service.FieldError) {
+	resp := problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+		Errors:   fields,
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log(r).WithError(err).Error("failed to write problem response")
+	}
+}
+
+// writeError отвечает на ошибку сервисного слоя. Типизированные ошибки
+// отдаются клиенту как есть, остальные логируются с msg и скрываются за 500.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) {
+		if svcErr.RateLimit != nil {
+			setRateLimitHeaders(w, svcErr.RateLimit)
+		}
+		h.writeProblem(w, r, statusForKind(svcErr.Kind), svcErr.Code, svcErr.Message, svcErr.Fields...)
+		return
+	}
+
+	h.log(r).WithError(err).Error(msg)
+	h.writeProblem(w, r, http.StatusInternalServerError, "internal_error", "server error")
+}
+
+func (h *Handler) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	h.writeProblem(w, r, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+}
+
+func statusForKind(kind service.ErrorKind) int {
+	switch kind {
+	case service.KindValidation:
+		return http.StatusBadRequest
+	case service.KindNotFound:
+		return http.StatusNotFound
+	case service.KindConflict:
+		return http.StatusConflict
+	case service.KindUnauthorized:
+		return http.StatusUnauthorized
+	case service.KindForbidden:
+		return http.StatusForbidden
+	case service.KindRateLimited:
+		return http.StatusTooManyRequests
+	case service.KindPrecondition:
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+)
+
+// файлы импорта читаются потоком, но тело всё равно ограничено
+const maxImportBody = 64 << 20
+
+// Импорт держит транзакцию открытой, пока клиент загружает файл, поэтому
+// медленная загрузка обрывается через maxImportDuration (64 МБ — это
+// около 0,5 МБ/с).
+const maxImportDuration = 2 * time.Minute
+
+// incidentEncoder пишет инциденты экспорта в ответ по одному.
+type incidentEncoder interface {
+	ContentType() string
+	Begin() error
+	Encode(in *model.Incident) error
+	End() error
+}
+
+// POST /api/v1/incidents:import — импорт из GeoJSON, CSV или KML. Формат
+// задаётся параметром format или Content-Type. Импорт атомарный: при любой
+// ошибке не создаётся ничего, а в ответе перечислены ошибки всех элементов.
+func (h *Handler) IncidentsImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.methodNotAllowed(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), maxImportDuration)
+	defer cancel()
+	// ctx не прерывает уже начатое чтение тела — это делает дедлайн соединения
+	_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(maxImportDuration))
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatFromContentType(r.Header.Get("Content-Type"))
+	}
+	body := http.MaxBytesReader(w, r.Body, maxImportBody)
+
+	var (
+		reader service.IncidentReader
+		path   string
+		names  map[string]string
+	)
+	switch format {
+	case "geojson":
+		reader, path, names = newGeoJSONReader(body), "features", geoJSONFieldNames
+	case "csv":
+		opts, err := parseCSVOptions(r.URL.Query())
+		if err != nil {
+			h.writeError(w, r, err, "invalid csv options")
+			return
+		}
+		csvReader, err := newCSVReader(body, opts)
+		if err != nil {
+			h.writeImportError(w, r, err)
+			return
+		}
+		reader, path, names = csvReader, "rows", opts.columns
+	case "kml":
+		reader, path, names = newKMLReader(body), "placemarks", kmlFieldNames
+	default:
+		h.writeProblem(w, r, http.StatusUnsupportedMediaType, "unsupported_format",
+			"import accepts GeoJSON, CSV or KML")
+		return
+	}
+
+	result, err := h.service.ImportIncidents(ctx, reader)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		h.writeImportError(w, r, importFieldErrors(err, path, names))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		h.log(r).WithError(err).Error("failed to write response")
+	}
+}
+
+func (h *Handler) writeImportError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.writeProblem(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large")
+		return
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		h.writeProblem(w, r, http.StatusRequestTimeout, "import_timeout",
+			"import did not finish in "+maxImportDuration.String())
+		return
+	}
+	h.writeError(w, r, err, "error importing incidents")
+}
+
+func formatFromContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "", "application/json", "application/geo+json":
+		return "geojson"
+	case "text/csv":
+		return "csv"
+	case "application/vnd.google-earth.kml+xml", "application/xml", "text/xml":
+		return "kml"
+	default:
+		return mediaType
+	}
+}
+
+// importFieldErrors переводит поля ошибок импорта ("[3].radius_m") в пути
+// внутри файла ("features[3].properties.radius_m"). names — имена полей
+// модели в формате файла; поля, которых там нет, остаются как есть.
+func importFieldErrors(err error, path string, names map[string]string) error {
+	var svcErr *service.Error
+	if !errors.As(err, &svcErr) || svcErr.Kind != service.KindValidation || len(svcErr.Fields) == 0 {
+		return err
+	}
+
+	fields := make([]service.FieldError, 0, len(svcErr.Fields))
+	for _, f := range svcErr.Fields {
+		index, name, ok := strings.Cut(f.Field, ".")
+		if !ok {
+			fields = append(fields, f)
+			continue
+		}
+		base, rest := name, ""
+		if i := strings.Index(name, "["); i >= 0 {
+			base, rest = name[:i], name[i:]
+		}
+		if mapped, ok := names[base]; ok {
+			name = mapped + rest
+		}
+		fields = append(fields, service.FieldError{Field: path + index + "." + name, Message: f.Message})
+	}
+	return service.NewValidationError(fields...)
+}
+
+// invalidFile — ошибка формата файла импорта целиком, а не его элемента.
+func invalidFile(code, message string) error {
+	return &service.Error{Kind: service.KindValidation, Code: code, Message: message}
+}
+
+// GET /api/v1/incidents:export?format=geojson|csv|kml — активные инциденты.
+// Ответ пишется потоком, по мере чтения из базы.
+func (h *Handler) IncidentsExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	var enc incidentEncoder
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "geojson":
+		enc = newGeoJSONEncoder(w)
+	case "csv":
+		opts, err := parseCSVOptions(r.URL.Query())
+		if err != nil {
+			h.writeError(w, r, err, "invalid csv options")
+			return
+		}
+		enc = newCSVEncoder(w, opts)
+	case "kml":
+		enc = newKMLEncoder(w)
+	default:
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid format parameter",
+			service.FieldError{Field: "format", Message: "must be geojson, csv or kml"})
+		return
+	}
+
+	// заголовок ответа откладываем до первого инцидента, чтобы ошибка
+	// запроса к базе ещё могла стать 500
+	started := false
+	begin := func() error {
+		started = true
+		w.Header().Set("Content-Type", enc.ContentType())
+		w.WriteHeader(http.StatusOK)
+		return enc.Begin()
+	}
+
+	err := h.service.ExportIncidents(r.Context(), func(in *model.Incident) error {
+		if !started {
+			if err := begin(); err != nil {
+				return err
+			}
+		}
+		return enc.Encode(in)
+	})
+	if err == nil && !started {
+		err = begin()
+	}
+	if err == nil {
+		err = enc.End()
+	}
+	if err != nil {
+		if !started {
+			h.writeError(w, r, err, "error exporting incidents")
+			return
+		}
+		// ответ уже начат — остаётся оборвать его
+		h.log(r).WithError(err).Error("incident export interrupted")
+	}
+}
+
+// readerError делает из ошибки разбора файла ошибку формата; превышение
+// лимита тела и дедлайн чтения возвращаются как есть, чтобы ответить 413
+// и 408.
+func readerError(err error, code, message string) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return err
+	}
+	return invalidFile(code, message+": "+err.Error())
+}
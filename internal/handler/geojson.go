@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+)
+
+// имена полей модели внутри объекта GeoJSON, для ошибок импорта
+var geoJSONFieldNames = map[string]string{
+	"title":       "properties.title",
+	"description": "properties.description",
+	"radius_m":    "properties.radius_m",
+	"latitude":    "geometry.coordinates",
+	"longitude":   "geometry.coordinates",
+	"polygon":     "geometry.coordinates",
+}
+
+type geoJSONFeature struct {
+	Type       string           `json:"type"`
+	Geometry   *geoJSONGeometry `json:"geometry"`
+	Properties json.RawMessage  `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// свойства объекта при импорте; центр и форма зоны берутся из геометрии
+type geoJSONImportProperties struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	RadiusM     *int   `json:"radius_m"`
+}
+
+// свойства объекта при экспорте
+type geoJSONExportProperties struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	RadiusM     int       `json:"radius_m"`
+	Version     int64     `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// geoJSONReader читает FeatureCollection потоком: объекты массива features
+// разбираются по одному, остальные ключи верхнего уровня пропускаются.
+type geoJSONReader struct {
+	dec         *json.Decoder
+	opened      bool
+	inFeatures  bool
+	hasFeatures bool
+	hasType     bool
+}
+
+func newGeoJSONReader(r io.Reader) *geoJSONReader {
+	return &geoJSONReader{dec: json.NewDecoder(r)}
+}
+
+func (gr *geoJSONReader) Next() (*model.Incident, []service.FieldError, error) {
+	if !gr.opened {
+		if err := gr.expect(json.Delim('{')); err != nil {
+			return nil, nil, err
+		}
+		gr.opened = true
+	}
+
+	for {
+		if gr.inFeatures {
+			if gr.dec.More() {
+				return gr.nextFeature()
+			}
+			if err := gr.expect(json.Delim(']')); err != nil {
+				return nil, nil, err
+			}
+			gr.inFeatures = false
+			continue
+		}
+
+		tok, err := gr.dec.Token()
+		if err != nil {
+			return nil, nil, gr.fail(err)
+		}
+		if tok == json.Delim('}') {
+			if !gr.hasType || !gr.hasFeatures {
+				return nil, nil, errNotFeatureCollection
+			}
+			return nil, nil, io.EOF
+		}
+
+		switch tok {
+		case "type":
+			var typ string
+			if err := gr.dec.Decode(&typ); err != nil || typ != "FeatureCollection" {
+				return nil, nil, errNotFeatureCollection
+			}
+			gr.hasType = true
+		case "features":
+			if err := gr.expect(json.Delim('[')); err != nil {
+				return nil, nil, err
+			}
+			gr.inFeatures, gr.hasFeatures = true, true
+		default:
+			var skip json.RawMessage
+			if err := gr.dec.Decode(&skip); err != nil {
+				return nil, nil, gr.fail(err)
+			}
+		}
+	}
+}
+
+var errNotFeatureCollection = invalidFile("invalid_geojson", "body must be a GeoJSON FeatureCollection")
+
+func (gr *geoJSONReader) nextFeature() (*model.Incident, []service.FieldError, error) {
+	var f geoJSONFeature
+	if err := gr.dec.Decode(&f); err != nil {
+		// объект неверного вида прочитан целиком — это ошибка самого объекта
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, []service.FieldError{{Field: typeErr.Field, Message: "has invalid type"}}, nil
+		}
+		return nil, nil, gr.fail(err)
+	}
+	in, fields := incidentFromFeature(f)
+	return in, fields, nil
+}
+
+func (gr *geoJSONReader) expect(delim json.Delim) error {
+	tok, err := gr.dec.Token()
+	if err != nil {
+		return gr.fail(err)
+	}
+	if tok != delim {
+		return errNotFeatureCollection
+	}
+	return nil
+}
+
+func (gr *geoJSONReader) fail(err error) error {
+	return readerError(err, "invalid_json", "invalid JSON")
+}
+
+// incidentFromFeature разбирает объект GeoJSON; ошибки — с путём внутри
+// объекта (geometry.coordinates) или с именем поля модели (radius_m).
+func incidentFromFeature(f geoJSONFeature) (*model.Incident, []service.FieldError) {
+	var in model.Incident
+	var errs []service.FieldError
+	fail := func(field, message string) {
+		errs = append(errs, service.FieldError{Field: field, Message: message})
+	}
+
+	if f.Type != "Feature" {
+		fail("type", "must be Feature")
+	}
+
+	var props geoJSONImportProperties
+	if len(f.Properties) > 0 && string(f.Properties) != "null" {
+		if err := json.Unmarshal(f.Properties, &props); err != nil {
+			fail("properties", "has invalid type")
+		}
+	}
+	in.Title = props.Title
+	in.Description = props.Description
+
+	if f.Geometry == nil {
+		fail("geometry", "is required")
+		return &in, errs
+	}
+	switch f.Geometry.Type {
+	case "Point":
+		var pt []float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &pt); err != nil || len(pt) < 2 {
+			fail("geometry.coordinates", "must be [longitude, latitude]")
+		} else if !validLonLat(pt[0], pt[1]) {
+			fail("geometry.coordinates", "are out of range")
+		} else {
+			in.Longitude, in.Latitude = pt[0], pt[1]
+		}
+		if props.RadiusM == nil {
+			fail("radius_m", "is required for Point")
+		} else {
+			in.RadiusM = *props.RadiusM
+		}
+	case "Polygon":
+		if err := json.Unmarshal(f.Geometry.Coordinates, &in.Polygon); err != nil || len(in.Polygon) == 0 {
+			fail("geometry.coordinates", "must be an array of linear rings")
+		}
+	default:
+		fail("geometry.type", "must be Point or Polygon")
+	}
+	return &in, errs
+}
+
+func validLonLat(lon, lat float64) bool {
+	return lon >= -180 && lon <= 180 && lat >= -90 && lat <= 90
+}
+
+// geoJSONEncoder пишет FeatureCollection по одному объекту.
+type geoJSONEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func newGeoJSONEncoder(w io.Writer) *geoJSONEncoder {
+	return &geoJSONEncoder{w: w}
+}
+
+func (e *geoJSONEncoder) ContentType() string {
+	return "application/geo+json"
+}
+
+func (e *geoJSONEncoder) Begin() error {
+	_, err := io.WriteString(e.w, `{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (e *geoJSONEncoder) Encode(in *model.Incident) error {
+	data, err := json.Marshal(incidentFeature(in))
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *geoJSONEncoder) End() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+func incidentFeature(in *model.Incident) any {
+	geometry := map[string]any{
+		"type":        "Point",
+		"coordinates": [2]float64{in.Longitude, in.Latitude},
+	}
+	if len(in.Polygon) > 0 {
+		geometry = map[string]any{
+			"type":        "Polygon",
+			"coordinates": in.Polygon,
+		}
+	}
+
+	return map[string]any{
+		"type":     "Feature",
+		"id":       in.ID,
+		"geometry": geometry,
+		"properties": geoJSONExportProperties{
+			Title:       in.Title,
+			Description: in.Description,
+			RadiusM:     in.RadiusM,
+			Version:     in.Version,
+			CreatedAt:   in.CreatedAt,
+			UpdatedAt:   in.UpdatedAt,
+		},
+	}
+}
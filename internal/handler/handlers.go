@@ -2,20 +2,29 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"geo-notifications/internal/auth"
 	"geo-notifications/internal/model"
 	"geo-notifications/internal/service"
 
 	"github.com/sirupsen/logrus"
 )
 
+// как часто шлём SSE-комментарий, чтобы прокси не рвали соединение
+const sseKeepAlive = 15 * time.Second
+
 type Handler struct {
 	logger             *logrus.Logger
 	service            service.IncidentService // без *
 	statsWindowMinutes int
+	alertsOrigins      []string
 }
 
 func NewHandler(logger *logrus.Logger, svc service.IncidentService, statsWindowMinutes int) *Handler {
@@ -28,7 +37,7 @@ func NewHandler(logger *logrus.Logger, svc service.IncidentService, statsWindowM
 
 func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		h.methodNotAllowed(w, r)
 		return
 	}
 	status := "ok"
@@ -67,21 +76,78 @@ func (h *Handler) IncidentsHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		h.ListIncidents(w, r)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		h.methodNotAllowed(w, r)
 	}
 }
 
+// parsePage разбирает page (по умолчанию 1) и page_size (по умолчанию 20);
+// при ошибке сам отвечает 400.
+func (h *Handler) parsePage(w http.ResponseWriter, r *http.Request) (page, pageSize int, ok bool) {
+	q := r.URL.Query()
+
+	page = 1
+	if v := q.Get("page"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 1 {
+			h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid page parameter",
+				service.FieldError{Field: "page", Message: "must be a positive integer"})
+			h.log(r).WithError(err).Info("error parsing page parameter")
+			return 0, 0, false
+		}
+		page = p
+	}
+
+	pageSize = 20
+	if v := q.Get("page_size"); v != "" {
+		ps, err := strconv.Atoi(v)
+		if err != nil || ps < 1 {
+			h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid page_size parameter",
+				service.FieldError{Field: "page_size", Message: "must be a positive integer"})
+			h.log(r).WithError(err).Info("error parsing page_size parameter")
+			return 0, 0, false
+		}
+		pageSize = ps
+	}
+	return page, pageSize, true
+}
+
 func (h *Handler) IncidentByIDHandler(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(parts) < 3 {
-		http.NotFound(w, r)
+
+	// /api/v1/incidents/{id}, /api/v1/incidents/{id}/history|stats или
+	// /api/v1/incidents/{id}:<action>
+	var sub string
+	if len(parts) == 5 {
+		sub = parts[4]
+		parts = parts[:4]
+	}
+	idStr, action, _ := strings.Cut(parts[len(parts)-1], ":")
+	if len(parts) != 4 || (sub != "" && sub != "history" && sub != "stats") || (sub != "" && action != "") {
+		h.writeProblem(w, r, http.StatusNotFound, "not_found", "not found")
 		return
 	}
 
-	idStr := parts[len(parts)-1]
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		http.Error(w, "invalid incident id", http.StatusBadRequest)
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid incident id",
+			service.FieldError{Field: "id", Message: "must be a positive integer"})
+		return
+	}
+
+	if sub != "" {
+		if r.Method != http.MethodGet {
+			h.methodNotAllowed(w, r)
+			return
+		}
+		if sub == "stats" {
+			h.GetIncidentStats(w, r, id)
+			return
+		}
+		h.GetIncidentHistory(w, r, id)
+		return
+	}
+	if action != "" {
+		h.incidentAction(w, r, id, action)
 		return
 	}
 
@@ -90,16 +156,18 @@ func (h *Handler) IncidentByIDHandler(w http.ResponseWriter, r *http.Request) {
 		h.GetIncidentByID(w, r, id)
 	case http.MethodPut:
 		h.UpdateIncident(w, r, id)
+	case http.MethodPatch:
+		h.PatchIncident(w, r, id)
 	case http.MethodDelete:
 		h.DeactivateIncident(w, r, id)
 	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		h.methodNotAllowed(w, r)
 	}
 }
 
 func (h *Handler) LocationHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		h.methodNotAllowed(w, r)
 		return
 	}
 
@@ -107,49 +175,98 @@ func (h *Handler) LocationHandler(w http.ResponseWriter, r *http.Request) {
 
 	var req model.LocationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.WithError(err).Error("invalid request body in LocationHandler")
-		http.Error(w, "invalid request body to location check", http.StatusBadRequest)
+		h.log(r).WithError(err).Error("invalid request body in LocationHandler")
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_json", "invalid request body to location check")
 		return
 	}
 
+	// пользователь с JWT проверяет только свою локацию
+	if userID, ok, err := auth.PrincipalFromContext(r.Context()).SubjectUserID(); ok {
+		if err != nil {
+			h.writeProblem(w, r, http.StatusForbidden, "invalid_subject", err.Error())
+			return
+		}
+		if req.UserID != 0 && req.UserID != userID {
+			h.writeProblem(w, r, http.StatusForbidden, "user_id_mismatch", "user_id does not match token subject",
+				service.FieldError{Field: "user_id", Message: "must match token subject"})
+			return
+		}
+		req.UserID = userID
+	}
+
 	locations, err := h.service.CheckLocations(r.Context(), req)
 	if err != nil {
-		h.logger.WithError(err).Error("error while checking location")
-		http.Error(w, "location check error", http.StatusInternalServerError)
+		h.writeError(w, r, err, "error while checking location")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(locations); err != nil {
-		h.logger.WithError(err).Error("error while writing response to location check request")
+		h.log(r).WithError(err).Error("error while writing response to location check request")
 	}
 }
 
+// GET /api/v1/incidents/stats: интервал задаётся from/to (RFC 3339) или
+// окном window до to; по умолчанию — STATS_TIME_WINDOW_MINUTES до текущего
+// момента.
 func (h *Handler) IncidentsStatsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		h.methodNotAllowed(w, r)
 		return
 	}
 
-	minutes := h.statsWindowMinutes
-
-	count, err := h.service.GetUserStats(r.Context(), minutes)
-	if err != nil {
-		h.logger.WithError(err).Error("failed to get incidents stats")
-		http.Error(w, "server error", http.StatusInternalServerError)
+	query, fields := h.parseStatsQuery(r.URL.Query())
+	if len(fields) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid stats parameters", fields...)
 		return
 	}
 
-	resp := struct {
-		UserCount int `json:"user_count"`
-	}{
-		UserCount: count,
+	stats, err := h.service.GetIncidentsStats(r.Context(), query)
+	if err != nil {
+		h.writeError(w, r, err, "failed to get incidents stats")
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(resp)
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+func (h *Handler) parseStatsQuery(q url.Values) (service.StatsQuery, []service.FieldError) {
+	query := service.StatsQuery{To: time.Now().UTC(), Bucket: q.Get("bucket")}
+	var fields []service.FieldError
+
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fields = append(fields, service.FieldError{Field: "to", Message: "must be an RFC 3339 timestamp"})
+		}
+		query.To = t
+	}
+
+	window := time.Duration(h.statsWindowMinutes) * time.Minute
+	if v := q.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			fields = append(fields, service.FieldError{Field: "window", Message: "must be a positive duration such as 15m or 24h"})
+		}
+		if q.Get("from") != "" {
+			fields = append(fields, service.FieldError{Field: "window", Message: "cannot be combined with from"})
+		}
+		window = d
+	}
+
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			fields = append(fields, service.FieldError{Field: "from", Message: "must be an RFC 3339 timestamp"})
+		}
+		query.From = t
+	} else {
+		query.From = query.To.Add(-window)
+	}
+	return query, fields
 }
 
 func (h *Handler) CreateIncident(w http.ResponseWriter, r *http.Request) {
@@ -157,53 +274,32 @@ func (h *Handler) CreateIncident(w http.ResponseWriter, r *http.Request) {
 
 	var incident model.Incident
 	if err := json.NewDecoder(r.Body).Decode(&incident); err != nil {
-		h.logger.WithError(err).Info("invalid request body in CreateIncident")
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		h.log(r).WithError(err).Info("invalid request body in CreateIncident")
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_json", "invalid JSON")
 		return
 	}
 
 	if err := h.service.CreateIncident(r.Context(), &incident); err != nil {
-		h.logger.WithError(err).Info("error in service CreateIncident call")
-		http.Error(w, "server error", http.StatusInternalServerError)
+		h.writeError(w, r, err, "error in service CreateIncident call")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(incident); err != nil {
-		h.logger.WithError(err).Error("failed to write response")
+		h.log(r).WithError(err).Error("failed to write response")
 	}
 }
 
 func (h *Handler) ListIncidents(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-
-	page := 1
-	if v := q.Get("page"); v != "" {
-		p, err := strconv.Atoi(v)
-		if err != nil || p < 1 {
-			http.Error(w, "invalid page parameter", http.StatusBadRequest)
-			h.logger.WithError(err).Info("error parsing page parameter")
-			return
-		}
-		page = p
-	}
-
-	pageSize := 20
-	if v := q.Get("page_size"); v != "" {
-		ps, err := strconv.Atoi(v)
-		if err != nil || ps < 1 {
-			http.Error(w, "invalid page_size parameter", http.StatusBadRequest)
-			h.logger.WithError(err).Info("error parsing page_size parameter")
-			return
-		}
-		pageSize = ps
+	page, pageSize, ok := h.parsePage(w, r)
+	if !ok {
+		return
 	}
 
 	items, err := h.service.GetItemsList(r.Context(), page, pageSize)
 	if err != nil {
-		h.logger.WithError(err).Info("error while getting list of incidents")
-		http.Error(w, "server error", http.StatusInternalServerError)
+		h.writeError(w, r, err, "error while getting list of incidents")
 		return
 	}
 
@@ -225,49 +321,261 @@ func (h *Handler) ListIncidents(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetIncidentByID(w http.ResponseWriter, r *http.Request, id int64) {
 	incident, err := h.service.GetIncidentByID(r.Context(), id)
 	if err != nil {
-		h.logger.WithError(err).Error("error getting incident by id")
-		http.Error(w, "server error", http.StatusInternalServerError)
+		h.writeError(w, r, err, "error getting incident by id")
 		return
 	}
-	if incident == nil {
-		http.NotFound(w, r)
+
+	w.Header().Set("ETag", incidentETag(incident))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(incident)
+}
+
+// GET /api/v1/incidents/{id}/history
+func (h *Handler) GetIncidentHistory(w http.ResponseWriter, r *http.Request, id int64) {
+	history, err := h.service.GetIncidentHistory(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, err, "error getting incident history")
 		return
 	}
 
+	resp := struct {
+		Items []model.IncidentChange `json:"items"`
+	}{Items: history}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(incident)
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// GET /api/v1/incidents/{id}/stats
+func (h *Handler) GetIncidentStats(w http.ResponseWriter, r *http.Request, id int64) {
+	stats, err := h.service.GetIncidentStats(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, err, "error getting incident stats")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
+// PUT /api/v1/incidents/{id}; If-Match обязателен: ETag из GET или "*"
 func (h *Handler) UpdateIncident(w http.ResponseWriter, r *http.Request, id int64) {
 	defer r.Body.Close()
 
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		h.writeProblem(w, r, http.StatusPreconditionRequired, "precondition_required", "If-Match header is required")
+		return
+	}
+	version, ok := parseIfMatch(ifMatch)
+	if !ok {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_if_match", "If-Match must be a strong ETag of the incident or *")
+		return
+	}
+
 	var incident model.Incident
 	if err := json.NewDecoder(r.Body).Decode(&incident); err != nil {
-		h.logger.WithError(err).Info("invalid request body in UpdateIncident")
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		h.log(r).WithError(err).Info("invalid request body in UpdateIncident")
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_json", "invalid JSON")
 		return
 	}
 	incident.ID = id
 
-	if err := h.service.UpdateIncident(r.Context(), &incident); err != nil {
-		h.logger.WithError(err).Error("error updating incident")
-		http.Error(w, "server error", http.StatusInternalServerError)
+	updated, err := h.service.UpdateIncident(r.Context(), &incident, version)
+	if err != nil {
+		h.writeError(w, r, err, "error updating incident")
 		return
 	}
 
+	w.Header().Set("ETag", incidentETag(updated))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(updated)
+}
+
+// PATCH /api/v1/incidents/{id} — JSON Merge Patch (RFC 7396): меняются
+// только переданные поля. If-Match необязателен.
+func (h *Handler) PatchIncident(w http.ResponseWriter, r *http.Request, id int64) {
+	defer r.Body.Close()
+
+	var version int64
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		var ok bool
+		if version, ok = parseIfMatch(ifMatch); !ok {
+			h.writeProblem(w, r, http.StatusBadRequest, "invalid_if_match", "If-Match must be a strong ETag of the incident or *")
+			return
+		}
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil || raw == nil {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_json", "merge patch must be a JSON object")
+		return
+	}
+	patch, err := decodeIncidentPatch(raw)
+	if err != nil {
+		h.writeError(w, r, err, "invalid merge patch")
+		return
+	}
+
+	incident, err := h.service.PatchIncident(r.Context(), id, patch, version)
+	if err != nil {
+		h.writeError(w, r, err, "error patching incident")
+		return
+	}
+
+	w.Header().Set("ETag", incidentETag(incident))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(incident)
 }
 
+// decodeIncidentPatch переводит merge patch в model.IncidentPatch. null
+// удаляет только description (становится пустым) и polygon, у остальных
+// полей значения по умолчанию нет.
+func decodeIncidentPatch(raw map[string]json.RawMessage) (*model.IncidentPatch, error) {
+	var patch model.IncidentPatch
+	var fields []service.FieldError
+	for name, value := range raw {
+		isNull := string(value) == "null"
+		var target any
+		switch name {
+		case "title":
+			target = &patch.Title
+		case "description":
+			if isNull {
+				empty := ""
+				patch.Description = &empty
+				continue
+			}
+			target = &patch.Description
+		case "latitude":
+			target = &patch.Latitude
+		case "longitude":
+			target = &patch.Longitude
+		case "radius_m":
+			target = &patch.RadiusM
+		case "active":
+			target = &patch.Active
+		case "polygon":
+			if isNull {
+				var none model.Polygon
+				patch.Polygon = &none
+				continue
+			}
+			target = &patch.Polygon
+		case "id", "version", "created_at", "updated_at":
+			fields = append(fields, service.FieldError{Field: name, Message: "is read-only"})
+			continue
+		default:
+			fields = append(fields, service.FieldError{Field: name, Message: "unknown field"})
+			continue
+		}
+		if isNull {
+			fields = append(fields, service.FieldError{Field: name, Message: "must not be null"})
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			fields = append(fields, service.FieldError{Field: name, Message: "has invalid type"})
+		}
+	}
+	if len(fields) > 0 {
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+		return nil, service.NewValidationError(fields...)
+	}
+	return &patch, nil
+}
+
+func incidentETag(in *model.Incident) string {
+	return `"` + strconv.FormatInt(in.Version, 10) + `"`
+}
+
+// parseIfMatch разбирает If-Match: "*" — любая версия (0), иначе ровно
+// один сильный ETag инцидента.
+func parseIfMatch(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	if v == "*" {
+		return 0, true
+	}
+	if len(v) < 3 || v[0] != '"' || v[len(v)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(v[1:len(v)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
 // DELETE /api/v1/incidents/{id} (деактивация)
 func (h *Handler) DeactivateIncident(w http.ResponseWriter, r *http.Request, id int64) {
 	if err := h.service.DeactivateIncident(r.Context(), id); err != nil {
-		h.logger.WithError(err).Error("error deactivating incident")
-		http.Error(w, "server error", http.StatusInternalServerError)
+		h.writeError(w, r, err, "error deactivating incident")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GET /api/v1/events (Server-Sent Events)
+func (h *Handler) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeProblem(w, r, http.StatusInternalServerError, "streaming_unsupported", "streaming unsupported")
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	events, err := h.service.SubscribeEvents(r.Context(), lastEventID)
+	if err != nil {
+		h.writeError(w, r, err, "failed to subscribe to events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				h.log(r).WithError(err).Error("failed to marshal event")
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
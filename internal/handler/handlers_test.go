@@ -3,7 +3,10 @@ package handler
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"geo-notifications/internal/auth"
 	"geo-notifications/internal/config"
 	"geo-notifications/internal/model"
 	"geo-notifications/internal/repository"
@@ -12,8 +15,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -34,11 +40,13 @@ func TestIncidentsHandlerSuccessCase(t *testing.T) {
 	defer storage.Close()
 
 	ctx := context.Background()
-	if err := storage.CreateTables(ctx); err != nil {
-		t.Fatalf("failed to create tables: %v", err)
+	if _, err := storage.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
 	}
 
-	incidentService := service.NewIncidentService(storage, logger)
+	incidentService := service.NewIncidentService(storage, logger, service.Config{
+		AlertsSecret: config.GetAlertsTokenSecret(),
+	})
 
 	statsMinutes := 10
 	if v := os.Getenv("STATS_TIME_WINDOW_MINUTES"); v != "" {
@@ -92,3 +100,680 @@ func TestIncidentsHandlerSuccessCase(t *testing.T) {
 		t.Errorf("expected title %q, got %q", incidentReq.Title, created.Title)
 	}
 }
+
+func TestTenantIsolation(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+
+	storage, err := repository.NewStorage(dbURL, redisCfg)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	if _, err := storage.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	incidentService := service.NewIncidentService(storage, logger, service.Config{})
+	router := NewRouter(NewHandler(logger, incidentService, 10))
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	tenantA := &auth.Principal{Name: "city-a", Role: auth.RoleAdmin, Tenant: "city-a-" + suffix}
+	tenantB := &auth.Principal{Name: "city-b", Role: auth.RoleAdmin, Tenant: "city-b-" + suffix}
+
+	do := func(p *auth.Principal, method, path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var reader io.Reader
+		if body != nil {
+			data, err := json.Marshal(body)
+			if err != nil {
+				t.Fatalf("failed to marshal body: %v", err)
+			}
+			reader = bytes.NewReader(data)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// инцидент тенанта A
+	w := do(tenantA, http.MethodPost, "/api/v1/incidents", model.Incident{
+		Title:     "Flood",
+		Latitude:  55.75,
+		Longitude: 37.61,
+		RadiusM:   1,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected %d, got %d, body=%s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created model.Incident
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal incident: %v", err)
+	}
+	byID := fmt.Sprintf("/api/v1/incidents/%d", created.ID)
+
+	t.Run("get by id", func(t *testing.T) {
+		if w := do(tenantB, http.MethodGet, byID, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("tenant B: expected %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if w := do(tenantA, http.MethodGet, byID, nil); w.Code != http.StatusOK {
+			t.Fatalf("tenant A: expected %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		w := do(tenantB, http.MethodGet, "/api/v1/incidents?page=1&page_size=100", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, w.Code)
+		}
+		var list struct {
+			Items []model.Incident `json:"items"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+			t.Fatalf("failed to unmarshal list: %v", err)
+		}
+		if len(list.Items) != 0 {
+			t.Fatalf("tenant B sees incidents of another tenant: %+v", list.Items)
+		}
+	})
+
+	t.Run("update and deactivate", func(t *testing.T) {
+		put := httptest.NewRequest(http.MethodPut, byID, strings.NewReader(`{"title":"Hijacked","radius_m":1}`))
+		put.Header.Set("If-Match", "*")
+		put = put.WithContext(auth.WithPrincipal(put.Context(), tenantB))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, put)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("update by tenant B: expected %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if w := do(tenantB, http.MethodDelete, byID, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("deactivate by tenant B: expected %d, got %d", http.StatusNotFound, w.Code)
+		}
+
+		w = do(tenantA, http.MethodGet, byID, nil)
+		var got model.Incident
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to unmarshal incident: %v", err)
+		}
+		if got.Title != "Flood" || !got.Active {
+			t.Fatalf("tenant B modified incident of tenant A: %+v", got)
+		}
+	})
+
+	check := func(p *auth.Principal) model.LocationResponse {
+		t.Helper()
+		w := do(p, http.MethodPost, "/api/v1/location/check", model.LocationRequest{UserID: 1, Latitude: 55.75, Longitude: 37.61})
+		if w.Code != http.StatusOK {
+			t.Fatalf("check: expected %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var resp model.LocationResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal location response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("location check", func(t *testing.T) {
+		if resp := check(tenantB); len(resp.LocationsIDS) != 0 {
+			t.Fatalf("tenant B matched incidents of another tenant: %v", resp.LocationsIDS)
+		}
+		resp := check(tenantA)
+		if len(resp.LocationsIDS) != 1 || resp.LocationsIDS[0] != created.ID {
+			t.Fatalf("tenant A: expected match with %d, got %v", created.ID, resp.LocationsIDS)
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		stats := func(p *auth.Principal) model.IncidentsStats {
+			w := do(p, http.MethodGet, "/api/v1/incidents/stats?bucket=minute", nil)
+			var resp model.IncidentsStats
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal stats: %v", err)
+			}
+			return resp
+		}
+		a := stats(tenantA)
+		if a.UserCount != 1 || a.MatchingChecks < 1 || a.TotalChecks < a.MatchingChecks {
+			t.Fatalf("tenant A: unexpected totals %+v", a)
+		}
+		found := false
+		for _, m := range a.Incidents {
+			found = found || (m.IncidentID == created.ID && m.Matches >= 1 && m.UniqueUsers == 1)
+		}
+		if !found {
+			t.Fatalf("tenant A: expected matches of incident %d, got %+v", created.ID, a.Incidents)
+		}
+		if len(a.Series) == 0 {
+			t.Fatalf("tenant A: expected a series")
+		}
+		b := stats(tenantB)
+		if b.UserCount != 0 || b.MatchingChecks != 0 || len(b.Incidents) != 0 {
+			t.Fatalf("tenant B: expected no matches, got %+v", b)
+		}
+
+		path := fmt.Sprintf("/api/v1/incidents/%d/stats", created.ID)
+		w := do(tenantA, http.MethodGet, path, nil)
+		var reach model.IncidentStats
+		if err := json.Unmarshal(w.Body.Bytes(), &reach); err != nil {
+			t.Fatalf("failed to unmarshal incident stats: %v", err)
+		}
+		if reach.UniqueUsers != 1 || reach.FirstMatchAt == nil || len(reach.Hourly) == 0 {
+			t.Fatalf("tenant A: unexpected incident stats %+v", reach)
+		}
+		if w := do(tenantB, http.MethodGet, path, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("tenant B: expected 404 for incident stats, got %d", w.Code)
+		}
+	})
+}
+
+func TestRateLimitAcrossReplicas(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+	cfg := service.Config{
+		RateLimits: service.RateLimits{
+			Routes: map[string]service.RateLimit{
+				"/api/v1/location/check": {Limit: 3, Period: time.Minute},
+			},
+		},
+	}
+
+	// две «реплики» со своими подключениями к общим Postgres и Redis
+	var replicas []http.Handler
+	for i := 0; i < 2; i++ {
+		storage, err := repository.NewStorage(dbURL, redisCfg)
+		if err != nil {
+			t.Fatalf("failed to init storage: %v", err)
+		}
+		defer storage.Close()
+		if _, err := storage.Migrate(context.Background()); err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+
+		h := NewHandler(logger, service.NewIncidentService(storage, logger, cfg), 10)
+		replicas = append(replicas, h.RateLimitMiddleware(NewRouter(h)))
+	}
+
+	principal := &auth.Principal{
+		Name:    "rate-limited",
+		Subject: "1",
+		Role:    auth.RoleReporter,
+		Tenant:  "rl-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+	check := func(replica http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/location/check", bytes.NewReader([]byte(`{}`)))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		w := httptest.NewRecorder()
+		replica.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, replica := range []http.Handler{replicas[0], replicas[1], replicas[0]} {
+		if w := check(replica); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected %d, got %d, body=%s", i+1, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	w := check(replicas[1])
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected %d once the shared bucket is empty, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected Retry-After header")
+	}
+}
+
+func TestIdempotentIncidentCreation(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+
+	storage, err := repository.NewStorage(dbURL, redisCfg)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	defer storage.Close()
+
+	if _, err := storage.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	h := NewHandler(logger, service.NewIncidentService(storage, logger, service.Config{}), 10)
+	srv := h.IdempotencyMiddleware(NewRouter(h))
+
+	key := "it-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	create := func(title string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"title":%q,"latitude":1,"longitude":1,"radius_m":1}`, title)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents", bytes.NewReader([]byte(body)))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	first := create("Retried")
+	second := create("Retried")
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("expected both responses %d, got %d and %d", http.StatusCreated, first.Code, second.Code)
+	}
+
+	var a, b model.Incident
+	if err := json.Unmarshal(first.Body.Bytes(), &a); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if err := json.Unmarshal(second.Body.Bytes(), &b); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if a.ID == 0 || a.ID != b.ID {
+		t.Fatalf("expected the same incident to be returned, got %d and %d", a.ID, b.ID)
+	}
+
+	if w := create("Different"); w.Code != http.StatusConflict {
+		t.Fatalf("expected %d for reused key, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestIncidentHistory(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+
+	storage, err := repository.NewStorage(dbURL, redisCfg)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	defer storage.Close()
+
+	if _, err := storage.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	router := NewRouter(NewHandler(logger, service.NewIncidentService(storage, logger, service.Config{}), 10))
+	dispatcher := &auth.Principal{KeyID: 7, Name: "console", Role: auth.RoleDispatcher, Tenant: auth.DefaultTenant}
+
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		req = req.WithContext(auth.WithPrincipal(req.Context(), dispatcher))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/v1/incidents", `{"title":"Fire","radius_m":1}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected %d, got %d, body=%s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created model.Incident
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal incident: %v", err)
+	}
+	byID := "/api/v1/incidents/" + strconv.FormatInt(created.ID, 10)
+
+	if w := do(http.MethodPatch, byID, `{"radius_m":2}`, nil); w.Code != http.StatusOK {
+		t.Fatalf("patch: expected %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := do(http.MethodDelete, byID, "", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("deactivate: expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+	// повторная деактивация ничего не меняет и в историю не попадает
+	do(http.MethodDelete, byID, "", nil)
+
+	w = do(http.MethodGet, byID+"/history", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("history: expected %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var history struct {
+		Items []model.IncidentChange `json:"items"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &history); err != nil {
+		t.Fatalf("failed to unmarshal history: %v", err)
+	}
+
+	want := []string{model.HistoryCreated, model.HistoryUpdated, model.HistoryDeactivated}
+	if len(history.Items) != len(want) {
+		t.Fatalf("expected %d history rows, got %+v", len(want), history.Items)
+	}
+	for i, c := range history.Items {
+		if c.Action != want[i] || c.Actor != "key:7" || c.Version != int64(i+1) {
+			t.Fatalf("unexpected history row %d: %+v", i, c)
+		}
+	}
+	radius := history.Items[1].Changes["radius_m"]
+	if len(history.Items[1].Changes) != 1 || radius.From != float64(1) || radius.To != float64(2) {
+		t.Fatalf("unexpected update diff: %+v", history.Items[1].Changes)
+	}
+}
+
+func TestPurgeRemovesReferences(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+	ctx := context.Background()
+
+	storage, err := repository.NewStorage(dbURL, redisCfg)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	defer storage.Close()
+	if _, err := storage.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	router := NewRouter(NewHandler(logger, service.NewIncidentService(storage, logger, service.Config{}), 10))
+	admin := &auth.Principal{KeyID: 7, Name: "console", Role: auth.RoleAdmin, Tenant: auth.DefaultTenant}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), admin))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/v1/incidents", `{"title":"Fire","latitude":55.75,"longitude":37.61,"radius_m":100}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected %d, got %d, body=%s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created model.Incident
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal incident: %v", err)
+	}
+	byID := "/api/v1/incidents/" + strconv.FormatInt(created.ID, 10)
+
+	if w := do(http.MethodPost, "/api/v1/location/check", `{"user_id":1,"latitude":55.75,"longitude":37.61}`); w.Code != http.StatusOK {
+		t.Fatalf("check: expected %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if err := storage.RecordWebhookDelivery(ctx, auth.DefaultTenant, []int64{created.ID}, http.StatusOK, ""); err != nil {
+		t.Fatalf("failed to record webhook delivery: %v", err)
+	}
+
+	if w := do(http.MethodDelete, byID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("deactivate: expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if w := do(http.MethodPost, byID+":purge", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("purge: expected %d, got %d, body=%s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	// incident_history намеренно хранит запись purged
+	for table, query := range map[string]string{
+		"incidents":          `SELECT COUNT(*) FROM incidents WHERE id = $1`,
+		"locations_check":    `SELECT COUNT(*) FROM locations_check WHERE incident_ids @> ARRAY[$1]::integer[]`,
+		"webhook_deliveries": `SELECT COUNT(*) FROM webhook_deliveries WHERE incident_ids @> ARRAY[$1]::integer[]`,
+	} {
+		var count int
+		if err := db.QueryRowContext(ctx, query, created.ID).Scan(&count); err != nil {
+			t.Fatalf("%s: %v", table, err)
+		}
+		if count != 0 {
+			t.Fatalf("%s still references purged incident %d (%d rows)", table, created.ID, count)
+		}
+	}
+}
+
+func TestUserAlertsLifecycle(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, err := repository.NewStorage(dbURL, redisCfg)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	defer storage.Close()
+	if _, err := storage.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := service.NewIncidentService(storage, logger, service.Config{AlertsSecret: "alerts-secret"})
+	go svc.RunEvents(ctx)
+	// даём подписке на Redis pub/sub подняться
+	time.Sleep(200 * time.Millisecond)
+
+	admin := auth.WithPrincipal(ctx, &auth.Principal{KeyID: 7, Name: "console", Role: auth.RoleAdmin, Tenant: auth.DefaultTenant})
+	userID := time.Now().UnixNano()%1_000_000 + 1_000_000
+	alerts, err := svc.SubscribeUserAlerts(ctx, auth.DefaultTenant, userID,
+		service.SignAlertsToken("alerts-secret", auth.DefaultTenant, userID))
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	next := func(wantType string, wantIncident int64, wantInside bool) {
+		t.Helper()
+		select {
+		case alert := <-alerts:
+			if alert.Type != wantType || alert.UserID != userID {
+				t.Fatalf("expected %s for user %d, got %+v", wantType, userID, alert)
+			}
+			if wantIncident != 0 && (alert.Incident == nil || alert.Incident.ID != wantIncident) {
+				t.Fatalf("%s: expected incident %d, got %+v", wantType, wantIncident, alert.Incident)
+			}
+			if inside := slices.Contains(alert.LocationsIDS, wantIncident); wantIncident != 0 && inside != wantInside {
+				t.Fatalf("%s: incident %d in locations_ids = %v, want %v", wantType, wantIncident, inside, wantInside)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", wantType)
+		}
+	}
+
+	first := &model.Incident{Title: "Fire", Latitude: 10.5, Longitude: -20.5, RadiusM: 1, Active: true}
+	if err := svc.CreateIncident(admin, first); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.CheckLocations(admin, model.LocationRequest{UserID: userID, Latitude: 10.5, Longitude: -20.5}); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	next(model.EventLocationMatched, first.ID, true)
+
+	second := &model.Incident{Title: "Flood", Latitude: 10.5, Longitude: -20.5, RadiusM: 2, Active: true}
+	if err := svc.CreateIncident(admin, second); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	next(model.EventIncidentCreated, second.ID, true)
+
+	title := "Flood, level 2"
+	if _, err := svc.PatchIncident(admin, second.ID, &model.IncidentPatch{Title: &title}, 0); err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	next(model.EventIncidentUpdated, second.ID, true)
+
+	if err := svc.DeactivateIncident(admin, first.ID); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	next(model.EventIncidentDeactivated, first.ID, false)
+
+	if _, err := svc.ReactivateIncident(admin, first.ID); err != nil {
+		t.Fatalf("reactivate: %v", err)
+	}
+	next(model.EventIncidentReactivated, first.ID, true)
+
+	if err := svc.DeactivateIncident(admin, first.ID); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	next(model.EventIncidentDeactivated, first.ID, false)
+	if err := svc.PurgeIncident(admin, first.ID); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	next(model.EventIncidentPurged, first.ID, false)
+
+	// инцидент вдали от пользователя алерта не даёт
+	far := &model.Incident{Title: "Far away", Latitude: -40, Longitude: 60, RadiusM: 1, Active: true}
+	if err := svc.CreateIncident(admin, far); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	select {
+	case alert := <-alerts:
+		t.Fatalf("unexpected alert: %+v", alert)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestTrashPurgerPublishesEvents(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, err := repository.NewStorage(dbURL, redisCfg)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	defer storage.Close()
+	if _, err := storage.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	svc := service.NewIncidentService(storage, logger, service.Config{})
+	go svc.RunEvents(ctx)
+	// даём подписке на Redis pub/sub подняться
+	time.Sleep(200 * time.Millisecond)
+
+	const tenant = "trash-purger"
+	admin := auth.WithPrincipal(ctx, &auth.Principal{KeyID: 7, Name: "console", Role: auth.RoleAdmin, Tenant: tenant})
+	events, err := svc.SubscribeEvents(admin, "")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	in := &model.Incident{Title: "Old fire", Latitude: 10.5, Longitude: -20.5, RadiusM: 1, Active: true}
+	if err := svc.CreateIncident(admin, in); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := svc.DeactivateIncident(admin, in.ID); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, `UPDATE incidents SET deactivated_at = now() - interval '2 days' WHERE id = $1`, in.ID); err != nil {
+		t.Fatalf("failed to backdate incident: %v", err)
+	}
+
+	go service.NewTrashPurger(storage, logger, 24*time.Hour).Run(ctx)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type != model.EventIncidentPurged || ev.Incident == nil || ev.Incident.ID != in.ID {
+				continue
+			}
+			if ev.Tenant != tenant {
+				t.Fatalf("expected tenant %q, got %q", tenant, ev.Tenant)
+			}
+			return
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s of incident %d", model.EventIncidentPurged, in.ID)
+		}
+	}
+}
+
+func TestPatchRemovePolygonRequiresRadius(t *testing.T) {
+	dbURL := config.GetDBURL()
+	redisCfg := config.GetRedisConfig()
+	if dbURL == "" || redisCfg.Addr == "" {
+		t.Skip("DATABASE_URL or REDIS_ADDR not set, skipping integration test")
+	}
+
+	logger := logrus.New()
+
+	storage, err := repository.NewStorage(dbURL, redisCfg)
+	if err != nil {
+		t.Fatalf("failed to init storage: %v", err)
+	}
+	defer storage.Close()
+	if _, err := storage.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	router := NewRouter(NewHandler(logger, service.NewIncidentService(storage, logger, service.Config{}), 10))
+	dispatcher := &auth.Principal{KeyID: 7, Name: "console", Role: auth.RoleDispatcher, Tenant: auth.DefaultTenant}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req = req.WithContext(auth.WithPrincipal(req.Context(), dispatcher))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/v1/incidents", `{"title":"Flood","polygon":[[[0,0],[1,0],[1,1],[0,0]]]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: expected %d, got %d, body=%s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created model.Incident
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal incident: %v", err)
+	}
+	if created.RadiusM != 0 {
+		t.Fatalf("expected radius 0 for a polygon incident, got %d", created.RadiusM)
+	}
+	byID := "/api/v1/incidents/" + strconv.FormatInt(created.ID, 10)
+
+	w = do(http.MethodPatch, byID, `{"polygon":null}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("patch: expected %d, got %d, body=%s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var body problem
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal problem: %v", err)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Field != "radius_m" {
+		t.Fatalf("unexpected field errors: %+v", body.Errors)
+	}
+
+	if w := do(http.MethodPatch, byID, `{"polygon":null,"radius_m":500}`); w.Code != http.StatusOK {
+		t.Fatalf("patch: expected %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
@@ -4,28 +4,68 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 
+	"geo-notifications/internal/auth"
 	"geo-notifications/internal/model"
 	"geo-notifications/internal/service"
 
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
 type fakeIncidentService struct {
 	healthErr       *service.HealthError
+	readiness       *model.Readiness
 	createdIncident *model.Incident
+	createErr       error
 	listItems       []model.Incident
+	events          []model.Event
+	alerts          []model.Alert
+	lastEventID     string
+	principals      map[string]*auth.Principal
+	createdKey      *model.APIKey
+	checkedLocation *model.LocationRequest
+	checkErr        error
+	rateLimited     map[string]bool
+	limitedRoutes   []string
+	createCalls     int
+	idempotency     map[string]*model.IdempotencyRecord
+	incidents       map[int64]*model.Incident
+	history         map[int64][]model.IncidentChange
+	importErr       error
+	statsQuery      *service.StatsQuery
+	heatmapQuery    *service.HeatmapQuery
 }
 
 func (f *fakeIncidentService) HealthCheck(ctx context.Context) *service.HealthError {
 	return f.healthErr
 }
 
+func (f *fakeIncidentService) Readiness(ctx context.Context) *model.Readiness {
+	if f.readiness != nil {
+		return f.readiness
+	}
+	return &model.Readiness{Status: model.ReadinessReady}
+}
+
 func (f *fakeIncidentService) CreateIncident(ctx context.Context, inc *model.Incident) error {
+	f.createCalls++
+	if f.createErr != nil {
+		return f.createErr
+	}
+	inc.ID = int64(f.createCalls)
 	f.createdIncident = inc
 	return nil
 }
@@ -35,23 +75,292 @@ func (f *fakeIncidentService) GetItemsList(ctx context.Context, page, pageSize i
 }
 
 func (f *fakeIncidentService) GetIncidentByID(ctx context.Context, id int64) (*model.Incident, error) {
-	return nil, nil
+	if inc, ok := f.incidents[id]; ok {
+		return inc, nil
+	}
+	return nil, service.NewNotFoundError("incident", id)
 }
 
 func (f *fakeIncidentService) GetUserStats(ctx context.Context, minutes int) (int, error) {
 	return 0, nil
 }
 
-func (f *fakeIncidentService) UpdateIncident(ctx context.Context, in *model.Incident) error {
-	return nil
+func (f *fakeIncidentService) GetIncidentsStats(ctx context.Context, q service.StatsQuery) (*model.IncidentsStats, error) {
+	f.statsQuery = &q
+	return &model.IncidentsStats{From: q.From, To: q.To, Bucket: q.Bucket}, nil
+}
+
+func (f *fakeIncidentService) GetHeatmap(ctx context.Context, q service.HeatmapQuery) (*model.Heatmap, error) {
+	f.heatmapQuery = &q
+	return &model.Heatmap{
+		Grid: "geohash", Precision: 5, From: q.From, To: q.To, MinUsers: 5, Suppressed: 2,
+		Cells: []model.HeatmapCell{{ID: "ucfv0", Bounds: [4]float64{37.6171875, 55.72265625, 37.6611328125, 55.7666015625}, Checks: 40, Users: 7}},
+	}, nil
+}
+
+func (f *fakeIncidentService) GetIncidentStats(ctx context.Context, id int64) (*model.IncidentStats, error) {
+	if _, ok := f.incidents[id]; !ok {
+		return nil, service.NewNotFoundError("incident", id)
+	}
+	first := time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC)
+	rate := 0.75
+	return &model.IncidentStats{
+		IncidentID:   id,
+		UniqueUsers:  2,
+		Matches:      3,
+		FirstMatchAt: &first,
+		LastMatchAt:  &first,
+		Hourly:       []model.ReachPoint{{Start: first.Truncate(time.Hour), Matches: 3, UniqueUsers: 2}},
+		Webhooks:     model.WebhookDeliveryStats{Attempts: 4, Succeeded: 3, SuccessRate: &rate},
+	}, nil
+}
+
+func (f *fakeIncidentService) UpdateIncident(ctx context.Context, in *model.Incident, ifVersion int64) (*model.Incident, error) {
+	current, ok := f.incidents[in.ID]
+	if !ok {
+		return nil, service.NewNotFoundError("incident", in.ID)
+	}
+	if ifVersion != 0 && ifVersion != current.Version {
+		return nil, service.ErrVersionMismatch
+	}
+	updated := *in
+	updated.Version = current.Version + 1
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = time.Now()
+	f.incidents[in.ID] = &updated
+	return &updated, nil
+}
+
+func (f *fakeIncidentService) PatchIncident(ctx context.Context, id int64, patch *model.IncidentPatch, ifVersion int64) (*model.Incident, error) {
+	current, ok := f.incidents[id]
+	if !ok {
+		return nil, service.NewNotFoundError("incident", id)
+	}
+	if ifVersion != 0 && ifVersion != current.Version {
+		return nil, service.ErrVersionMismatch
+	}
+	merged := *current
+	if patch.Title != nil {
+		merged.Title = *patch.Title
+	}
+	if patch.Description != nil {
+		merged.Description = *patch.Description
+	}
+	if patch.RadiusM != nil {
+		merged.RadiusM = *patch.RadiusM
+	}
+	if patch.Active != nil {
+		merged.Active = *patch.Active
+	}
+	if patch.Polygon != nil {
+		merged.Polygon = *patch.Polygon
+	}
+	// то же правило, что у сервиса: без полигона нужен радиус
+	if len(merged.Polygon) == 0 && merged.RadiusM <= 0 {
+		return nil, service.NewValidationError(service.FieldError{Field: "radius_m", Message: "must be positive for an incident without polygon"})
+	}
+	merged.Version++
+	f.incidents[id] = &merged
+	return &merged, nil
 }
 
 func (f *fakeIncidentService) DeactivateIncident(ctx context.Context, id int64) error {
+	if _, ok := f.incidents[id]; !ok {
+		return service.NewNotFoundError("incident", id)
+	}
+	return nil
+}
+
+func (f *fakeIncidentService) GetIncidentHistory(ctx context.Context, id int64) ([]model.IncidentChange, error) {
+	if _, ok := f.incidents[id]; !ok {
+		return nil, service.NewNotFoundError("incident", id)
+	}
+	return f.history[id], nil
+}
+
+func (f *fakeIncidentService) ReactivateIncident(ctx context.Context, id int64) (*model.Incident, error) {
+	inc, ok := f.incidents[id]
+	if !ok {
+		return nil, service.NewNotFoundError("incident", id)
+	}
+	if !inc.Active {
+		inc.Active = true
+		inc.DeactivatedAt = nil
+		inc.Version++
+	}
+	return inc, nil
+}
+
+func (f *fakeIncidentService) PurgeIncident(ctx context.Context, id int64) error {
+	inc, ok := f.incidents[id]
+	if !ok {
+		return service.NewNotFoundError("incident", id)
+	}
+	if inc.Active {
+		return service.ErrIncidentActive
+	}
+	delete(f.incidents, id)
+	return nil
+}
+
+func (f *fakeIncidentService) GetTrash(ctx context.Context, page, pageSize int) ([]model.Incident, error) {
+	var items []model.Incident
+	for _, inc := range f.incidents {
+		if !inc.Active {
+			items = append(items, *inc)
+		}
+	}
+	return items, nil
+}
+
+func (f *fakeIncidentService) ImportIncidents(ctx context.Context, r service.IncidentReader) (*model.ImportResult, error) {
+	var items []*model.Incident
+	var fields []service.FieldError
+	for i := 0; ; i++ {
+		in, itemFields, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, fe := range itemFields {
+			fields = append(fields, service.FieldError{Field: fmt.Sprintf("[%d].%s", i, fe.Field), Message: fe.Message})
+		}
+		items = append(items, in)
+	}
+	if len(fields) > 0 {
+		return nil, service.NewValidationError(fields...)
+	}
+	if f.importErr != nil {
+		return nil, f.importErr
+	}
+	if f.incidents == nil {
+		f.incidents = make(map[int64]*model.Incident)
+	}
+	result := &model.ImportResult{Created: len(items)}
+	for _, in := range items {
+		in.ID = int64(len(f.incidents) + 1)
+		in.Active = true
+		in.Version = 1
+		f.incidents[in.ID] = in
+		result.IDs = append(result.IDs, in.ID)
+	}
+	return result, nil
+}
+
+func (f *fakeIncidentService) ExportIncidents(ctx context.Context, fn func(*model.Incident) error) error {
+	ids := make([]int64, 0, len(f.incidents))
+	for id, inc := range f.incidents {
+		if inc.Active {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if err := fn(f.incidents[id]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (f *fakeIncidentService) CheckLocations(ctx context.Context, req model.LocationRequest) (model.LocationResponse, error) {
-	return model.LocationResponse{}, nil
+	if f.checkErr != nil {
+		return model.LocationResponse{}, f.checkErr
+	}
+	f.checkedLocation = &req
+	return model.LocationResponse{LocationRequest: req}, nil
+}
+
+func (f *fakeIncidentService) SubscribeEvents(ctx context.Context, lastEventID string) (<-chan model.Event, error) {
+	if lastEventID == "bad" {
+		return nil, service.ErrInvalidEventID
+	}
+	f.lastEventID = lastEventID
+
+	ch := make(chan model.Event, len(f.events))
+	for _, ev := range f.events {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (f *fakeIncidentService) SubscribeUserAlerts(ctx context.Context, tenant string, userID int64, token string) (<-chan model.Alert, error) {
+	if token != "secret-token" {
+		return nil, service.ErrInvalidToken
+	}
+
+	ch := make(chan model.Alert, len(f.alerts))
+	for _, alert := range f.alerts {
+		ch <- alert
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (f *fakeIncidentService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	if p, ok := f.principals[key]; ok {
+		return p, nil
+	}
+	return nil, service.ErrInvalidAPIKey
+}
+
+func (f *fakeIncidentService) AuthenticateBearerToken(ctx context.Context, token string) (*auth.Principal, error) {
+	if p, ok := f.principals["Bearer "+token]; ok {
+		return p, nil
+	}
+	return nil, service.ErrInvalidBearer
+}
+
+func (f *fakeIncidentService) AllowRequest(ctx context.Context, route, client string) (*service.RateLimitStatus, error) {
+	f.limitedRoutes = append(f.limitedRoutes, route)
+	if f.rateLimited[client] {
+		st := &service.RateLimitStatus{Limit: 10, Remaining: 0, Reset: time.Minute, RetryAfter: 1500 * time.Millisecond}
+		return st, service.NewRateLimitError(st)
+	}
+	return &service.RateLimitStatus{Limit: 10, Remaining: 9, Reset: 6 * time.Second}, nil
+}
+
+func (f *fakeIncidentService) BeginIdempotentRequest(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, error) {
+	if f.idempotency == nil {
+		f.idempotency = make(map[string]*model.IdempotencyRecord)
+	}
+	stored, ok := f.idempotency[key]
+	switch {
+	case !ok:
+		f.idempotency[key] = &model.IdempotencyRecord{Fingerprint: fingerprint}
+		return nil, nil
+	case stored.Fingerprint != fingerprint:
+		return nil, service.ErrIdempotencyKeyReused
+	case !stored.Done:
+		return nil, service.ErrIdempotencyInProgress
+	}
+	return stored, nil
+}
+
+func (f *fakeIncidentService) CompleteIdempotentRequest(ctx context.Context, key string, rec model.IdempotencyRecord) error {
+	rec.Done = true
+	f.idempotency[key] = &rec
+	return nil
+}
+
+func (f *fakeIncidentService) AbortIdempotentRequest(ctx context.Context, key string) {
+	delete(f.idempotency, key)
+}
+
+func (f *fakeIncidentService) CreateAPIKey(ctx context.Context, name, role, tenant string) (*model.APIKey, error) {
+	f.createdKey = &model.APIKey{ID: 1, Name: name, Role: role, Tenant: tenant, Prefix: "gn_abcdefgh", Key: "gn_abcdefgh-secret"}
+	return f.createdKey, nil
+}
+
+func (f *fakeIncidentService) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return nil, nil
+}
+
+func (f *fakeIncidentService) RevokeAPIKey(ctx context.Context, id int64) error {
+	return service.NewNotFoundError("api_key", id)
 }
 
 func TestHealthHandler_OK(t *testing.T) {
@@ -126,6 +435,65 @@ func TestHealthHandler_Degraded(t *testing.T) {
 	}
 }
 
+func TestProbes(t *testing.T) {
+	depth, age := int64(3), 1.5
+	tests := []struct {
+		name      string
+		path      string
+		readiness *model.Readiness
+		want      int
+	}{
+		{"live without dependencies", "/livez", &model.Readiness{Status: model.ReadinessNotReady}, http.StatusOK},
+		{"ready", "/readyz", &model.Readiness{
+			Status: model.ReadinessReady,
+			Checks: map[string]model.DependencyCheck{
+				"postgres": {Status: "ok", LatencyMs: 0.8},
+				"redis":    {Status: "ok", LatencyMs: 0.3},
+			},
+			WebhookQueueDepth:         &depth,
+			WorkerHeartbeatAgeSeconds: &age,
+		}, http.StatusOK},
+		{"dependency down", "/readyz", &model.Readiness{
+			Status: model.ReadinessNotReady,
+			Checks: map[string]model.DependencyCheck{
+				"postgres": {Status: "error", LatencyMs: 2000},
+				"redis":    {Status: "ok", LatencyMs: 0.3},
+			},
+		}, http.StatusServiceUnavailable},
+		{"shutting down", "/readyz", &model.Readiness{Status: model.ReadinessShuttingDown}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// лимиты на пробы не действуют
+			svc := &fakeIncidentService{readiness: tt.readiness, rateLimited: map[string]bool{"ip:192.0.2.1": true}}
+			h := NewHandler(logrus.New(), svc, 5)
+			srv := h.RateLimitMiddleware(NewRouter(h))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if len(svc.limitedRoutes) != 0 {
+				t.Fatalf("probe must not be rate limited, got %v", svc.limitedRoutes)
+			}
+			if tt.path != "/readyz" {
+				return
+			}
+			var body model.Readiness
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if !reflect.DeepEqual(&body, tt.readiness) {
+				t.Fatalf("unexpected body: %+v", body)
+			}
+		})
+	}
+}
+
 func TestIncidentsHandler_CreateIncident(t *testing.T) {
 	logger := logrus.New()
 	svc := &fakeIncidentService{}
@@ -190,3 +558,1008 @@ func TestIncidentsHandler_ListIncidents(t *testing.T) {
 		t.Fatalf("unexpected items: %+v", body.Items)
 	}
 }
+
+func TestEventsHandler_StreamsEvents(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{
+		events: []model.Event{
+			{ID: "1-0", Type: model.EventIncidentCreated, Incident: &model.Incident{ID: 7, Title: "fire"}},
+			{ID: "1-1", Type: model.EventLocationMatched, Match: &model.WebhookPayload{UserID: 3, LocationsIDS: []int64{7}}},
+		},
+	}
+	h := NewHandler(logger, svc, 5)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	req.Header.Set("Last-Event-ID", "0-5")
+	w := httptest.NewRecorder()
+
+	h.EventsHandler(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if svc.lastEventID != "0-5" {
+		t.Fatalf("Last-Event-ID was not passed to service: %q", svc.lastEventID)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"id: 1-0\nevent: incident.created\ndata: ",
+		"id: 1-1\nevent: location.matched\ndata: ",
+		`"title":"fire"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected body to contain %q, got %q", want, body)
+		}
+	}
+}
+
+func TestEventsHandler_InvalidLastEventID(t *testing.T) {
+	logger := logrus.New()
+	h := NewHandler(logger, &fakeIncidentService{}, 5)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events?last_event_id=bad", nil)
+	w := httptest.NewRecorder()
+
+	h.EventsHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAlertsWSHandler_SendsAlerts(t *testing.T) {
+	logger := logrus.New()
+	checkedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	match := model.WebhookPayload{
+		UserID: 5, Latitude: 55.75, Longitude: 37.61,
+		LocationsIDS: []int64{1, 2}, CheckedAt: checkedAt, Tenant: "default",
+	}
+	svc := &fakeIncidentService{
+		alerts: []model.Alert{
+			{Type: model.EventLocationMatched, WebhookPayload: match},
+			{
+				Type:           model.EventIncidentPurged,
+				WebhookPayload: model.WebhookPayload{UserID: 5, LocationsIDS: []int64{1}, CheckedAt: checkedAt, Tenant: "default"},
+				Incident:       &model.Incident{ID: 2},
+			},
+		},
+	}
+	h := NewHandler(logger, svc, 5)
+
+	srv := httptest.NewServer(http.HandlerFunc(h.AlertsWSHandler))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/alerts/ws?user_id=5&token=secret-token"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	// совпадение приходит телом вебхука с полем type
+	var got map[string]json.RawMessage
+	if err := conn.ReadJSON(&got); err != nil {
+		t.Fatalf("failed to read alert: %v", err)
+	}
+	webhook, _ := json.Marshal(match)
+	var want map[string]json.RawMessage
+	_ = json.Unmarshal(webhook, &want)
+	want["type"] = json.RawMessage(`"location.matched"`)
+	if len(got) != len(want) {
+		t.Fatalf("unexpected alert fields: %v", got)
+	}
+	for k, v := range want {
+		if string(got[k]) != string(v) {
+			t.Fatalf("field %s: expected %s, got %s", k, v, got[k])
+		}
+	}
+
+	var alert model.Alert
+	if err := conn.ReadJSON(&alert); err != nil {
+		t.Fatalf("failed to read alert: %v", err)
+	}
+	if alert.Type != model.EventIncidentPurged || alert.Incident == nil || alert.Incident.ID != 2 || len(alert.LocationsIDS) != 1 {
+		t.Fatalf("unexpected alert: %+v", alert)
+	}
+}
+
+func TestAlertsWSHandler_Origin(t *testing.T) {
+	h := NewHandler(logrus.New(), &fakeIncidentService{}, 5)
+	h.SetAlertsOrigins([]string{"https://m.example.com"})
+
+	srv := httptest.NewServer(http.HandlerFunc(h.AlertsWSHandler))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/alerts/ws?user_id=5&token=secret-token"
+
+	tests := []struct {
+		name   string
+		origin string
+		ok     bool
+	}{
+		{"no origin", "", true},
+		{"same host", srv.URL, true},
+		{"allowed", "https://m.example.com", true},
+		{"allowed case-insensitive", "HTTPS://M.EXAMPLE.COM", true},
+		{"foreign", "https://evil.example.com", false},
+		{"allowed host other scheme", "http://m.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("expected upgrade, got %v", err)
+				}
+				conn.Close()
+				return
+			}
+			if err == nil {
+				conn.Close()
+				t.Fatal("expected origin to be rejected")
+			}
+			if resp == nil || resp.StatusCode != http.StatusForbidden {
+				t.Fatalf("expected 403, got %v", resp)
+			}
+		})
+	}
+}
+
+func TestAlertsWSHandler_InvalidToken(t *testing.T) {
+	logger := logrus.New()
+	h := NewHandler(logger, &fakeIncidentService{}, 5)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/alerts/ws?user_id=5&token=wrong", nil)
+	w := httptest.NewRecorder()
+
+	h.AlertsWSHandler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestIncidentsHandler_CreateIncidentValidationProblem(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{
+		createErr: service.NewValidationError(service.FieldError{Field: "title", Message: "is required"}),
+	}
+	h := NewHandler(logger, svc, 5)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents", strings.NewReader(`{"radius_m":10}`))
+	w := httptest.NewRecorder()
+
+	h.IncidentsHandler(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	var body problem
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body.Code != "validation_failed" || body.Status != http.StatusBadRequest {
+		t.Fatalf("unexpected problem: %+v", body)
+	}
+	if len(body.Errors) != 1 || body.Errors[0].Field != "title" {
+		t.Fatalf("unexpected field errors: %+v", body.Errors)
+	}
+}
+
+func TestIncidentByIDHandler_NotFoundProblem(t *testing.T) {
+	logger := logrus.New()
+	h := NewHandler(logger, &fakeIncidentService{}, 5)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/incidents/13", nil)
+	w := httptest.NewRecorder()
+
+	h.IncidentByIDHandler(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	var body problem
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body.Code != "incident_not_found" || body.Instance != "/api/v1/incidents/13" {
+		t.Fatalf("unexpected problem: %+v", body)
+	}
+}
+
+func TestIncidentByIDHandler_IfMatch(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{
+		7: {ID: 7, Title: "t", RadiusM: 100, Active: true, Version: 3},
+	}}
+	h := NewHandler(logger, svc, 5)
+
+	get := httptest.NewRecorder()
+	h.IncidentByIDHandler(get, httptest.NewRequest(http.MethodGet, "/api/v1/incidents/7", nil))
+	if get.Code != http.StatusOK || get.Header().Get("ETag") != `"3"` {
+		t.Fatalf("expected 200 with ETag \"3\", got %d %q", get.Code, get.Header().Get("ETag"))
+	}
+
+	body := `{"title":"t2","radius_m":200,"active":true}`
+	tests := []struct {
+		name     string
+		ifMatch  string
+		wantCode int
+		wantETag string
+	}{
+		{"missing", "", http.StatusPreconditionRequired, ""},
+		{"weak", `W/"3"`, http.StatusBadRequest, ""},
+		{"stale", `"2"`, http.StatusPreconditionFailed, ""},
+		{"current", `"3"`, http.StatusOK, `"4"`},
+		{"any", "*", http.StatusOK, `"5"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/incidents/7", strings.NewReader(body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			h.IncidentByIDHandler(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Fatalf("expected ETag %q, got %q", tt.wantETag, got)
+			}
+		})
+	}
+}
+
+func TestIncidentByIDHandler_UnknownIncident(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{}}
+	h := NewHandler(logger, svc, 5)
+
+	put := httptest.NewRequest(http.MethodPut, "/api/v1/incidents/13", strings.NewReader(`{"title":"t","active":true}`))
+	put.Header.Set("If-Match", "*")
+	del := httptest.NewRequest(http.MethodDelete, "/api/v1/incidents/13", nil)
+
+	for _, req := range []*http.Request{put, del} {
+		w := httptest.NewRecorder()
+		h.IncidentByIDHandler(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected status %d, got %d", req.Method, http.StatusNotFound, w.Code)
+		}
+	}
+}
+
+func TestIncidentByIDHandler_PutReturnsPersisted(t *testing.T) {
+	logger := logrus.New()
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{
+		7: {ID: 7, Title: "t", Active: true, Version: 1, CreatedAt: created, UpdatedAt: created},
+	}}
+	h := NewHandler(logger, svc, 5)
+
+	// клиент прислал свои даты — в ответе должны быть сохранённые
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/incidents/7",
+		strings.NewReader(`{"title":"t2","active":true,"created_at":"2000-01-01T00:00:00Z"}`))
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+	h.IncidentByIDHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var got model.Incident
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if !got.CreatedAt.Equal(created) || !got.UpdatedAt.After(created) || got.Version != 2 {
+		t.Fatalf("expected persisted incident, got %+v", got)
+	}
+}
+
+func TestIncidentByIDHandler_History(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{
+		incidents: map[int64]*model.Incident{7: {ID: 7, Title: "t", Version: 2}},
+		history: map[int64][]model.IncidentChange{7: {
+			{ID: 1, IncidentID: 7, Action: model.HistoryCreated, Actor: "key:1", Version: 1},
+			{ID: 2, IncidentID: 7, Action: model.HistoryUpdated, Actor: "jwt:default:42", Version: 2,
+				Changes: map[string]model.FieldChange{"radius_m": {From: 100, To: 200}}},
+		}},
+	}
+	h := NewHandler(logger, svc, 5)
+
+	w := httptest.NewRecorder()
+	h.IncidentByIDHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/incidents/7/history", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp struct {
+		Items []model.IncidentChange `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if len(resp.Items) != 2 || resp.Items[1].Actor != "jwt:default:42" || resp.Items[1].Changes["radius_m"].To != float64(200) {
+		t.Fatalf("unexpected history: %+v", resp.Items)
+	}
+
+	tests := []struct {
+		method, path string
+		wantCode     int
+	}{
+		{http.MethodGet, "/api/v1/incidents/13/history", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/incidents/7/history", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/v1/incidents/7/unknown", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.IncidentByIDHandler(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.wantCode, w.Code)
+		}
+	}
+}
+
+func TestIncidentByIDHandler_Stats(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{7: {ID: 7, Title: "t", Version: 1}}}
+	h := NewHandler(logger, svc, 5)
+
+	w := httptest.NewRecorder()
+	h.IncidentByIDHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/incidents/7/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var stats model.IncidentStats
+	if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if stats.IncidentID != 7 || stats.UniqueUsers != 2 || len(stats.Hourly) != 1 ||
+		stats.Webhooks.SuccessRate == nil || *stats.Webhooks.SuccessRate != 0.75 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	tests := []struct {
+		method, path string
+		wantCode     int
+	}{
+		{http.MethodGet, "/api/v1/incidents/13/stats", http.StatusNotFound},
+		{http.MethodDelete, "/api/v1/incidents/7/stats", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/v1/incidents/7:purge/stats", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.IncidentByIDHandler(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s %s: expected status %d, got %d", tt.method, tt.path, tt.wantCode, w.Code)
+		}
+	}
+}
+
+func TestIncidentByIDHandler_ReactivateAndPurge(t *testing.T) {
+	logger := logrus.New()
+	deactivatedAt := time.Now()
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{
+		7: {ID: 7, Title: "active", Active: true, Version: 1},
+		8: {ID: 8, Title: "trashed", Version: 2, DeactivatedAt: &deactivatedAt},
+	}}
+	router := NewRouter(NewHandler(logger, svc, 5))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/v1/incidents/trash")
+	var trash struct {
+		Items []model.Incident `json:"items"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&trash); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("trash: status %d, err %v", w.Code, err)
+	}
+	if len(trash.Items) != 1 || trash.Items[0].ID != 8 {
+		t.Fatalf("expected only deactivated incident in trash, got %+v", trash.Items)
+	}
+
+	if w := do(http.MethodPost, "/api/v1/incidents/7:purge"); w.Code != http.StatusConflict {
+		t.Fatalf("purge of active incident: expected %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	w = do(http.MethodPost, "/api/v1/incidents/8:reactivate")
+	if w.Code != http.StatusOK || w.Header().Get("ETag") != `"3"` {
+		t.Fatalf("reactivate: expected 200 with ETag \"3\", got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	if !svc.incidents[8].Active || svc.incidents[8].DeactivatedAt != nil {
+		t.Fatalf("incident was not reactivated: %+v", svc.incidents[8])
+	}
+
+	svc.incidents[8].Active = false
+	if w := do(http.MethodPost, "/api/v1/incidents/8:purge"); w.Code != http.StatusNoContent {
+		t.Fatalf("purge: expected %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if _, ok := svc.incidents[8]; ok {
+		t.Fatalf("incident was not purged")
+	}
+
+	if w := do(http.MethodPost, "/api/v1/incidents/7:archive"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown action: expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := do(http.MethodGet, "/api/v1/incidents/7:reactivate"); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET action: expected %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestIncidentByIDHandler_MergePatch(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{
+		7: {ID: 7, Title: "t", Description: "d", RadiusM: 100, Active: true, Version: 1},
+	}}
+	h := NewHandler(logger, svc, 5)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/incidents/7", strings.NewReader(`{"title":"t2","description":null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	h.IncidentByIDHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var got model.Incident
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	// не переданные поля не обнуляются
+	if got.Title != "t2" || got.Description != "" || got.RadiusM != 100 || !got.Active {
+		t.Fatalf("unexpected incident after patch: %+v", got)
+	}
+	if w.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected ETag \"2\", got %q", w.Header().Get("ETag"))
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"not an object", `[1]`, http.StatusBadRequest},
+		{"null radius", `{"radius_m":null}`, http.StatusBadRequest},
+		{"read-only", `{"id":8}`, http.StatusBadRequest},
+		{"unknown field", `{"color":"red"}`, http.StatusBadRequest},
+		{"wrong type", `{"active":"yes"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/incidents/7", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			h.IncidentByIDHandler(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIncidentByIDHandler_MergePatchRemovePolygon(t *testing.T) {
+	logger := logrus.New()
+	square := model.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{
+		8: {ID: 8, Title: "flood", Polygon: square, RadiusM: 0, Active: true, Version: 1},
+	}}
+	h := NewHandler(logger, svc, 5)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/incidents/8", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		w := httptest.NewRecorder()
+		h.IncidentByIDHandler(w, req)
+		return w
+	}
+
+	// без полигона и радиуса зона пуста
+	w := patch(`{"polygon":null}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var body problem
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body.Code != "validation_failed" || len(body.Errors) != 1 || body.Errors[0].Field != "radius_m" {
+		t.Fatalf("unexpected problem: %+v", body)
+	}
+	if got := svc.incidents[8]; len(got.Polygon) == 0 || got.Version != 1 {
+		t.Fatalf("incident changed after rejected patch: %+v", got)
+	}
+
+	w = patch(`{"polygon":null,"radius_m":500}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := svc.incidents[8]; len(got.Polygon) != 0 || got.RadiusM != 500 {
+		t.Fatalf("unexpected incident after patch: %+v", got)
+	}
+}
+
+// importedIncidents читает ответ импорта и возвращает созданные инциденты
+// из fake-сервиса в порядке файла.
+func importedIncidents(t *testing.T, svc *fakeIncidentService, w *httptest.ResponseRecorder) []model.Incident {
+	t.Helper()
+	var result model.ImportResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if result.Created != len(result.IDs) {
+		t.Fatalf("created %d, but got %d ids", result.Created, len(result.IDs))
+	}
+	items := make([]model.Incident, 0, len(result.IDs))
+	for _, id := range result.IDs {
+		items = append(items, *svc.incidents[id])
+	}
+	return items
+}
+
+func TestIncidentsImportHandler_GeoJSON(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{}
+	router := NewRouter(NewHandler(logger, svc, 5))
+
+	body := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[49.1,55.8]},"properties":{"title":"fire","radius_m":300}},
+		{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]},"properties":{"title":"flood"}}
+	]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/incidents:import", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	items := importedIncidents(t, svc, w)
+	if len(items) != 2 || items[0].Title != "fire" || items[0].Longitude != 49.1 || items[0].RadiusM != 300 {
+		t.Fatalf("unexpected imported point: %+v", items)
+	}
+	if len(items[1].Polygon) != 1 || len(items[1].Polygon[0]) != 4 {
+		t.Fatalf("unexpected imported polygon: %+v", items[1])
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		serviceErr error
+		wantFields []string
+	}{
+		{
+			name: "invalid features",
+			body: `{"type":"FeatureCollection","features":[
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[49.1,55.8]},"properties":{"title":"no radius"}},
+				{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]},"properties":{"title":"line"}}
+			]}`,
+			wantFields: []string{"features[0].properties.radius_m", "features[1].geometry.type"},
+		},
+		{
+			name: "service validation",
+			body: `{"type":"FeatureCollection","features":[
+				{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]},"properties":{}}
+			]}`,
+			serviceErr: service.NewValidationError(
+				service.FieldError{Field: "[0].title", Message: "is required"},
+				service.FieldError{Field: "[0].polygon[0]", Message: "must have at least 4 positions"},
+			),
+			wantFields: []string{"features[0].properties.title", "features[0].geometry.coordinates[0]"},
+		},
+		{
+			name:       "not a collection",
+			body:       `{"type":"Feature"}`,
+			wantFields: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc.importErr = tt.serviceErr
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/incidents:import", strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+			var p problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatalf("failed to decode problem: %v", err)
+			}
+			if len(p.Errors) != len(tt.wantFields) {
+				t.Fatalf("expected fields %v, got %+v", tt.wantFields, p.Errors)
+			}
+			for i, field := range tt.wantFields {
+				if p.Errors[i].Field != field {
+					t.Fatalf("expected fields %v, got %+v", tt.wantFields, p.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestIncidentsImportHandler_SlowUpload(t *testing.T) {
+	router := NewRouter(NewHandler(logrus.New(), &fakeIncidentService{}, 5))
+
+	// так чтение тела обрывает дедлайн соединения
+	bodies := map[string]io.Reader{
+		"csv":     io.MultiReader(strings.NewReader("title,latitude,longitude,radius_m\nfire,1,2,3\n"), iotest.ErrReader(os.ErrDeadlineExceeded)),
+		"geojson": io.MultiReader(strings.NewReader(`{"type":"FeatureCollection","features":[`), iotest.ErrReader(os.ErrDeadlineExceeded)),
+	}
+	for format, body := range bodies {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/incidents:import?format="+format, body))
+		if w.Code != http.StatusRequestTimeout || !strings.Contains(w.Body.String(), "import_timeout") {
+			t.Fatalf("%s: expected 408 import_timeout, got %d %s", format, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestIncidentsExportHandler_GeoJSON(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{
+		1: {ID: 1, Title: "fire", Latitude: 55.8, Longitude: 49.1, RadiusM: 300, Active: true, Version: 1},
+		2: {ID: 2, Title: "flood", Polygon: model.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, Active: true, Version: 1},
+		3: {ID: 3, Title: "old", Version: 2},
+	}}
+	router := NewRouter(NewHandler(logger, svc, 5))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/incidents:export", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/geo+json" {
+		t.Fatalf("expected 200 application/geo+json, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID       int64 `json:"id"`
+			Geometry struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+			Properties struct {
+				Title   string `json:"title"`
+				RadiusM int    `json:"radius_m"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&fc); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("expected collection of 2 active incidents, got %+v", fc)
+	}
+	if f := fc.Features[0]; f.ID != 1 || f.Geometry.Type != "Point" || f.Properties.RadiusM != 300 {
+		t.Fatalf("unexpected point feature: %+v", f)
+	}
+	if f := fc.Features[1]; f.ID != 2 || f.Geometry.Type != "Polygon" {
+		t.Fatalf("unexpected polygon feature: %+v", f)
+	}
+
+	svc.incidents = nil
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/incidents:export", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"features":[]`) {
+		t.Fatalf("expected empty collection, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIncidentsImportHandler_CSV(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{}
+	router := NewRouter(NewHandler(logger, svc, 5))
+
+	importCSV := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents:import"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// колонки переименованы, разделитель и десятичная запятая — как в Excel
+	body := "\ufeffНазвание;Широта;Долгота;Радиус;Комментарий\n" +
+		"Пожар;55,75;37,61;300;\"у моста; левый берег\"\n"
+	w := importCSV("?delimiter=%3B&columns=title:Название,latitude:Широта,longitude:Долгота,radius_m:Радиус,description:Комментарий", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	items := importedIncidents(t, svc, w)
+	if len(items) != 1 {
+		t.Fatalf("expected 1 incident, got %+v", items)
+	}
+	if in := items[0]; in.Title != "Пожар" || in.Latitude != 55.75 || in.Longitude != 37.61 || in.RadiusM != 300 || in.Description != "у моста; левый берег" {
+		t.Fatalf("unexpected imported incident: %+v", in)
+	}
+
+	w = importCSV("?columns=radius_m:radius", "title,latitude,longitude,radius\nok,1,2,3\nbad,x,2,\n")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var p problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Field != "rows[1].latitude" || p.Errors[1].Field != "rows[1].radius" {
+		t.Fatalf("expected errors of row 1 with CSV column names, got %+v", p.Errors)
+	}
+
+	if w := importCSV("", "title,description\nno coordinates,\n"); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing columns: expected %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := importCSV("?columns=color:Цвет", "title\n"); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown column mapping: expected %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestIncidentsImportHandler_KML(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{}
+	router := NewRouter(NewHandler(logger, svc, 5))
+
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2"><Document><Folder>
+  <Placemark>
+    <name>Пожар</name>
+    <ExtendedData><Data name="radius_m"><value>300</value></Data></ExtendedData>
+    <Point><coordinates>37.61,55.75,0</coordinates></Point>
+  </Placemark>
+  <Placemark>
+    <name>Паводок</name>
+    <Polygon>
+      <outerBoundaryIs><LinearRing><coordinates>0,0 4,0 4,4 0,0</coordinates></LinearRing></outerBoundaryIs>
+      <innerBoundaryIs><LinearRing><coordinates>1,1 2,1 2,2 1,1</coordinates></LinearRing></innerBoundaryIs>
+    </Polygon>
+  </Placemark>
+</Folder></Document></kml>`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents:import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/vnd.google-earth.kml+xml")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	items := importedIncidents(t, svc, w)
+	if len(items) != 2 || items[0].RadiusM != 300 || items[0].Longitude != 37.61 {
+		t.Fatalf("unexpected imported point: %+v", items)
+	}
+	if len(items[1].Polygon) != 2 || len(items[1].Polygon[1]) != 4 {
+		t.Fatalf("expected polygon with a hole, got %+v", items[1])
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/incidents:import?format=kml",
+		strings.NewReader(`<kml><Placemark><name>line</name><LineString><coordinates>0,0 1,1</coordinates></LineString></Placemark></kml>`))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"placemarks[0].geometry"`) {
+		t.Fatalf("expected placemark error, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestIncidentsExportHandler_CSVAndKML(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{incidents: map[int64]*model.Incident{
+		1: {ID: 1, Title: "fire, east", Latitude: 55.75, Longitude: 37.61, RadiusM: 300, Active: true, Version: 1},
+		2: {ID: 2, Title: "flood", Polygon: model.Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}, Active: true, Version: 1},
+		3: {ID: 3, Title: `=HYPERLINK("http://evil.example.com","fire")`, Description: "@SUM(A1)", RadiusM: 1, Active: true, Version: 1},
+	}}
+	router := NewRouter(NewHandler(logger, svc, 5))
+
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/incidents:export"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("export%s: expected status %d, got %d: %s", query, http.StatusOK, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	w := export("?format=csv&columns=title:Название")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "id,Название,description,") {
+		t.Fatalf("unexpected CSV export: %q", w.Body.String())
+	}
+	if !strings.HasPrefix(lines[1], `1,"fire, east",,55.75,37.61,300,,1,`) || !strings.Contains(lines[2], `"[[[0,0],[1,0],[1,1],[0,0]]]"`) {
+		t.Fatalf("unexpected CSV rows: %q", lines[1:])
+	}
+	// формулы открываются в таблице как текст
+	if !strings.HasPrefix(lines[3], `3,"'=HYPERLINK(""http://evil.example.com"",""fire"")",'@SUM(A1),`) {
+		t.Fatalf("formula is not neutralised: %q", lines[3])
+	}
+	// импорт снимает префикс обратно
+	opts, err := parseCSVOptions(url.Values{"columns": {"title:Название"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := newCSVReader(strings.NewReader(w.Body.String()), opts)
+	if err != nil {
+		t.Fatalf("failed to read CSV export: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := reader.Next(); err != nil {
+			t.Fatalf("row %d: %v", i, err)
+		}
+	}
+	if in, _, err := reader.Next(); err != nil || in.Title != svc.incidents[3].Title || in.Description != "@SUM(A1)" {
+		t.Fatalf("unexpected imported row: %+v, %v", in, err)
+	}
+
+	w = export("?format=kml")
+	if w.Header().Get("Content-Type") != "application/vnd.google-earth.kml+xml" {
+		t.Fatalf("unexpected KML content type %q", w.Header().Get("Content-Type"))
+	}
+	// экспорт читается импортом обратно
+	kml := newKMLReader(w.Body)
+	for i := 0; i < len(svc.incidents); i++ {
+		in, fields, err := kml.Next()
+		if err != nil || len(fields) > 0 {
+			t.Fatalf("placemark %d: %+v, %v", i, fields, err)
+		}
+		if want := svc.incidents[int64(i+1)]; in.Title != want.Title || in.RadiusM != want.RadiusM || len(in.Polygon) != len(want.Polygon) {
+			t.Fatalf("placemark %d: expected %+v, got %+v", i, want, in)
+		}
+	}
+	if _, _, err := kml.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected end of KML, got %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/incidents:export?format=shp", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown format: expected %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestIncidentsStatsHandler_Params(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{}
+	h := NewHandler(logger, svc, 5)
+
+	stats := func(query string) *httptest.ResponseRecorder {
+		svc.statsQuery = nil
+		w := httptest.NewRecorder()
+		h.IncidentsStatsHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/incidents/stats"+query, nil))
+		return w
+	}
+
+	// по умолчанию — окно STATS_TIME_WINDOW_MINUTES до текущего момента
+	if w := stats(""); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if q := svc.statsQuery; q.To.Sub(q.From) != 5*time.Minute || time.Since(q.To) > time.Minute {
+		t.Fatalf("unexpected default range: %+v", q)
+	}
+
+	if w := stats("?to=2026-01-02T00:00:00Z&window=24h&bucket=hour"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	want := service.StatsQuery{
+		From:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+		Bucket: "hour",
+	}
+	if q := svc.statsQuery; !q.From.Equal(want.From) || !q.To.Equal(want.To) || q.Bucket != want.Bucket {
+		t.Fatalf("expected %+v, got %+v", want, q)
+	}
+
+	for _, query := range []string{
+		"?from=yesterday",
+		"?window=-1h",
+		"?from=2026-01-01T00:00:00Z&window=1h",
+	} {
+		if w := stats(query); w.Code != http.StatusBadRequest || svc.statsQuery != nil {
+			t.Fatalf("%s: expected status %d without service call, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestHeatmapHandler(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{}
+	h := NewHandler(logger, svc, 5)
+
+	heatmap := func(query string) *httptest.ResponseRecorder {
+		svc.heatmapQuery = nil
+		w := httptest.NewRecorder()
+		h.HeatmapHandler(w, httptest.NewRequest(http.MethodGet, "/api/v1/location/heatmap"+query, nil))
+		return w
+	}
+
+	w := heatmap("?window=24h&grid=geohash&precision=5&matching=true&min_users=10&bbox=37.3,55.5,37.9,56.0")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/geo+json" {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+	q := svc.heatmapQuery
+	if q.Grid != "geohash" || q.Precision != 5 || !q.OnlyMatching || q.MinUsers != 10 ||
+		q.BBox == nil || *q.BBox != [4]float64{37.3, 55.5, 37.9, 56.0} || q.To.Sub(q.From) != 24*time.Hour {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+
+	var resp struct {
+		Type       string `json:"type"`
+		Suppressed int    `json:"suppressed_cells"`
+		Features   []struct {
+			ID       string `json:"id"`
+			Geometry struct {
+				Type        string         `json:"type"`
+				Coordinates [][][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				Users int `json:"users"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if resp.Type != "FeatureCollection" || resp.Suppressed != 2 || len(resp.Features) != 1 {
+		t.Fatalf("unexpected collection: %+v", resp)
+	}
+	f := resp.Features[0]
+	ring := f.Geometry.Coordinates[0]
+	if f.ID != "ucfv0" || f.Geometry.Type != "Polygon" || len(ring) != 5 || ring[0] != ring[4] ||
+		ring[2] != [2]float64{37.6611328125, 55.7666015625} || f.Properties.Users != 7 {
+		t.Fatalf("unexpected feature: %+v", f)
+	}
+
+	for _, query := range []string{
+		"?precision=fine",
+		"?min_users=0",
+		"?matching=maybe",
+		"?bbox=1,2,3",
+		"?from=yesterday",
+	} {
+		if w := heatmap(query); w.Code != http.StatusBadRequest || svc.heatmapQuery != nil {
+			t.Fatalf("%s: expected status %d without service call, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestIncidentsHandler_InternalErrorHidesDetails(t *testing.T) {
+	logger := logrus.New()
+	svc := &fakeIncidentService{createErr: errors.New("pq: connection refused")}
+	h := NewHandler(logger, svc, 5)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents", strings.NewReader(`{"title":"t"}`))
+	w := httptest.NewRecorder()
+
+	h.IncidentsHandler(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "connection refused") {
+		t.Fatalf("internal error leaked to client: %s", w.Body.String())
+	}
+
+	var body problem
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if body.Code != "internal_error" {
+		t.Fatalf("unexpected problem: %+v", body)
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+)
+
+// GET /api/v1/location/heatmap — проверки локаций по ячейкам сетки в виде
+// GeoJSON FeatureCollection для наложения на карту. Интервал задаётся так
+// же, как у /api/v1/incidents/stats.
+func (h *Handler) HeatmapHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	query, fields := h.parseHeatmapQuery(r.URL.Query())
+	if len(fields) > 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid heatmap parameters", fields...)
+		return
+	}
+
+	heatmap, err := h.service.GetHeatmap(r.Context(), query)
+	if err != nil {
+		h.writeError(w, r, err, "failed to get heatmap")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(heatmapCollection(heatmap)); err != nil {
+		h.log(r).WithError(err).Error("failed to write response")
+	}
+}
+
+func (h *Handler) parseHeatmapQuery(q url.Values) (service.HeatmapQuery, []service.FieldError) {
+	stats, fields := h.parseStatsQuery(q)
+	query := service.HeatmapQuery{From: stats.From, To: stats.To, Grid: q.Get("grid")}
+
+	if v := q.Get("precision"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			fields = append(fields, service.FieldError{Field: "precision", Message: "must be a positive integer"})
+		}
+		query.Precision = n
+	}
+	if v := q.Get("min_users"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			fields = append(fields, service.FieldError{Field: "min_users", Message: "must be a positive integer"})
+		}
+		query.MinUsers = n
+	}
+	if v := q.Get("matching"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			fields = append(fields, service.FieldError{Field: "matching", Message: "must be true or false"})
+		}
+		query.OnlyMatching = b
+	}
+	if v := q.Get("bbox"); v != "" {
+		parts := strings.Split(v, ",")
+		var bbox [4]float64
+		ok := len(parts) == 4
+		for i := 0; ok && i < 4; i++ {
+			var err error
+			bbox[i], err = strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+			ok = err == nil
+		}
+		if !ok {
+			fields = append(fields, service.FieldError{Field: "bbox", Message: "must be minLon,minLat,maxLon,maxLat"})
+		}
+		query.BBox = &bbox
+	}
+	return query, fields
+}
+
+type heatmapFeature struct {
+	Type       string            `json:"type"`
+	ID         string            `json:"id"`
+	Geometry   heatmapGeometry   `json:"geometry"`
+	Properties heatmapProperties `json:"properties"`
+}
+
+type heatmapGeometry struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+type heatmapProperties struct {
+	Cell   string `json:"cell"`
+	Checks int    `json:"checks"`
+	Users  int    `json:"users"`
+}
+
+// heatmapCollection — ячейки как прямоугольные полигоны; параметры сетки
+// лежат рядом с features как посторонние члены GeoJSON.
+func heatmapCollection(hm *model.Heatmap) any {
+	features := make([]heatmapFeature, 0, len(hm.Cells))
+	for _, c := range hm.Cells {
+		minLon, minLat, maxLon, maxLat := c.Bounds[0], c.Bounds[1], c.Bounds[2], c.Bounds[3]
+		features = append(features, heatmapFeature{
+			Type: "Feature",
+			ID:   c.ID,
+			Geometry: heatmapGeometry{
+				Type: "Polygon",
+				Coordinates: [][][2]float64{{
+					{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat},
+				}},
+			},
+			Properties: heatmapProperties{Cell: c.ID, Checks: c.Checks, Users: c.Users},
+		})
+	}
+
+	return struct {
+		Type       string           `json:"type"`
+		Grid       string           `json:"grid"`
+		Precision  int              `json:"precision"`
+		From       time.Time        `json:"from"`
+		To         time.Time        `json:"to"`
+		MinUsers   int              `json:"min_users"`
+		Suppressed int              `json:"suppressed_cells"`
+		Features   []heatmapFeature `json:"features"`
+	}{
+		Type:       "FeatureCollection",
+		Grid:       hm.Grid,
+		Precision:  hm.Precision,
+		From:       hm.From,
+		To:         hm.To,
+		MinUsers:   hm.MinUsers,
+		Suppressed: hm.Suppressed,
+		Features:   features,
+	}
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"geo-notifications/internal/model"
+)
+
+const maxIdempotentBody = 1 << 20
+
+// заголовки ответа, которые повторяются при воспроизведении
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// idempotentRoutes — POST-маршруты с поддержкой Idempotency-Key. Остальные
+// не подходят: импорт потоковый и больше maxIdempotentBody, а ответ с новым
+// API-ключом не должен храниться в Redis.
+var idempotentRoutes = map[string]bool{
+	"/api/v1/incidents":      true,
+	"/api/v1/location/check": true,
+}
+
+// IdempotencyMiddleware поддерживает заголовок Idempotency-Key на POST из
+// idempotentRoutes: первый ответ сохраняется и воспроизводится на повторы с
+// тем же телом, повтор с другим телом получает 409. Ключи — в пространстве
+// клиента, поэтому middleware стоит после AuthMiddleware.
+func (h *Handler) IdempotencyMiddleware(next http.Handler) http.Handler {
+	routes := h.Routes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rt, ok := matchRoute(routes, r.Method, r.URL.Path)
+		if !ok || !idempotentRoutes[rt.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+		if err != nil {
+			h.writeProblem(w, r, http.StatusBadRequest, "invalid_body", "failed to read request body")
+			return
+		}
+		if len(body) > maxIdempotentBody {
+			h.writeProblem(w, r, http.StatusRequestEntityTooLarge, "body_too_large", "request body is too large")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.New()
+		sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		sum.Write(body)
+		fingerprint := hex.EncodeToString(sum.Sum(nil))
+
+		scopedKey := clientID(r) + ":" + key
+		stored, err := h.service.BeginIdempotentRequest(r.Context(), scopedKey, fingerprint)
+		if err != nil {
+			h.writeError(w, r, err, "failed to check idempotency key")
+			return
+		}
+		if stored != nil {
+			for name, value := range stored.Header {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			_, _ = w.Write(stored.Body)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		// клиент мог уже отключиться, а ключ всё равно нужно закрыть
+		ctx := context.WithoutCancel(r.Context())
+
+		// ошибки сервера и лимиты временные — повтор должен выполниться заново
+		if rec.status >= http.StatusInternalServerError || rec.status == http.StatusTooManyRequests {
+			h.service.AbortIdempotentRequest(ctx, scopedKey)
+			return
+		}
+
+		resp := model.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      rec.status,
+			Header:      make(map[string]string),
+			Body:        rec.body.Bytes(),
+		}
+		for _, name := range replayedHeaders {
+			if v := w.Header().Get(name); v != "" {
+				resp.Header[name] = v
+			}
+		}
+		if err := h.service.CompleteIdempotentRequest(ctx, scopedKey, resp); err != nil {
+			// ответ клиент уже получил; без записи повтор выполнится заново
+			h.service.AbortIdempotentRequest(ctx, scopedKey)
+		}
+	})
+}
+
+// responseRecorder пропускает ответ клиенту, запоминая статус и тело.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+)
+
+// имена полей модели внутри Placemark, для ошибок импорта
+var kmlFieldNames = map[string]string{
+	"title":       "name",
+	"description": "description",
+	"radius_m":    "ExtendedData.radius_m",
+	"latitude":    "Point.coordinates",
+	"longitude":   "Point.coordinates",
+	"polygon":     "Polygon",
+}
+
+type kmlPlacemark struct {
+	XMLName     xml.Name    `xml:"Placemark"`
+	ID          string      `xml:"id,attr,omitempty"`
+	Name        string      `xml:"name"`
+	Description string      `xml:"description,omitempty"`
+	Data        []kmlData   `xml:"ExtendedData>Data"`
+	Point       *kmlPoint   `xml:"Point"`
+	Polygon     *kmlPolygon `xml:"Polygon"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	Outer kmlBoundary   `xml:"outerBoundaryIs"`
+	Inner []kmlBoundary `xml:"innerBoundaryIs"`
+}
+
+type kmlBoundary struct {
+	Coordinates string `xml:"LinearRing>coordinates"`
+}
+
+// kmlReader читает Placemark по одному, где бы они ни лежали (Document,
+// Folder).
+type kmlReader struct {
+	dec    *xml.Decoder
+	opened bool
+}
+
+func newKMLReader(r io.Reader) *kmlReader {
+	return &kmlReader{dec: xml.NewDecoder(r)}
+}
+
+func (kr *kmlReader) Next() (*model.Incident, []service.FieldError, error) {
+	for {
+		tok, err := kr.dec.Token()
+		if errors.Is(err, io.EOF) {
+			if !kr.opened {
+				return nil, nil, errNotKML
+			}
+			return nil, nil, io.EOF
+		}
+		if err != nil {
+			return nil, nil, readerError(err, "invalid_kml", "invalid KML")
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !kr.opened {
+			if start.Name.Local != "kml" {
+				return nil, nil, errNotKML
+			}
+			kr.opened = true
+			continue
+		}
+		if start.Name.Local != "Placemark" {
+			continue
+		}
+
+		var p kmlPlacemark
+		if err := kr.dec.DecodeElement(&p, &start); err != nil {
+			return nil, nil, readerError(err, "invalid_kml", "invalid KML")
+		}
+		in, fields := incidentFromPlacemark(p)
+		return in, fields, nil
+	}
+}
+
+var errNotKML = invalidFile("invalid_kml", "body must be a KML document")
+
+// incidentFromPlacemark: name — заголовок, зона — Point с ExtendedData
+// radius_m или Polygon.
+func incidentFromPlacemark(p kmlPlacemark) (*model.Incident, []service.FieldError) {
+	in := model.Incident{
+		Title:       strings.TrimSpace(p.Name),
+		Description: strings.TrimSpace(p.Description),
+	}
+	var errs []service.FieldError
+	fail := func(field, message string) {
+		errs = append(errs, service.FieldError{Field: field, Message: message})
+	}
+
+	switch {
+	case p.Point != nil:
+		coords, err := parseKMLCoordinates(p.Point.Coordinates)
+		if err != nil || len(coords) != 1 {
+			fail("Point.coordinates", "must be a single longitude,latitude tuple")
+		} else if !validLonLat(coords[0][0], coords[0][1]) {
+			fail("Point.coordinates", "are out of range")
+		} else {
+			in.Longitude, in.Latitude = coords[0][0], coords[0][1]
+		}
+
+		radius, ok := "", false
+		for _, d := range p.Data {
+			if d.Name == "radius_m" {
+				radius, ok = strings.TrimSpace(d.Value), true
+			}
+		}
+		if !ok {
+			fail("radius_m", "is required for Point")
+		} else if r, err := strconv.Atoi(radius); err != nil {
+			fail("radius_m", "must be an integer")
+		} else {
+			in.RadiusM = r
+		}
+	case p.Polygon != nil:
+		rings := append([]kmlBoundary{p.Polygon.Outer}, p.Polygon.Inner...)
+		for i, b := range rings {
+			ring, err := parseKMLCoordinates(b.Coordinates)
+			if err != nil {
+				fail("polygon["+strconv.Itoa(i)+"]", "must be a list of longitude,latitude tuples")
+				continue
+			}
+			in.Polygon = append(in.Polygon, ring)
+		}
+	default:
+		fail("geometry", "must be Point or Polygon")
+	}
+	return &in, errs
+}
+
+// parseKMLCoordinates разбирает "lon,lat[,alt] lon,lat[,alt] ...".
+func parseKMLCoordinates(s string) ([][2]float64, error) {
+	var out [][2]float64
+	for _, tuple := range strings.Fields(s) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, errors.New("invalid coordinate tuple")
+		}
+		lon, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, err
+		}
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, [2]float64{lon, lat})
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no coordinates")
+	}
+	return out, nil
+}
+
+func formatKMLCoordinates(ring [][2]float64) string {
+	tuples := make([]string, len(ring))
+	for i, pt := range ring {
+		tuples[i] = strconv.FormatFloat(pt[0], 'f', -1, 64) + "," + strconv.FormatFloat(pt[1], 'f', -1, 64)
+	}
+	return strings.Join(tuples, " ")
+}
+
+// kmlEncoder пишет Document с Placemark на каждый инцидент.
+type kmlEncoder struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+func newKMLEncoder(w io.Writer) *kmlEncoder {
+	return &kmlEncoder{w: w, enc: xml.NewEncoder(w)}
+}
+
+func (e *kmlEncoder) ContentType() string {
+	return "application/vnd.google-earth.kml+xml"
+}
+
+func (e *kmlEncoder) Begin() error {
+	_, err := io.WriteString(e.w, xml.Header+`<kml xmlns="http://www.opengis.net/kml/2.2"><Document>`)
+	return err
+}
+
+func (e *kmlEncoder) Encode(in *model.Incident) error {
+	p := kmlPlacemark{
+		ID:          "incident-" + strconv.FormatInt(in.ID, 10),
+		Name:        in.Title,
+		Description: in.Description,
+	}
+	if len(in.Polygon) > 0 {
+		p.Polygon = &kmlPolygon{Outer: kmlBoundary{Coordinates: formatKMLCoordinates(in.Polygon[0])}}
+		for _, hole := range in.Polygon[1:] {
+			p.Polygon.Inner = append(p.Polygon.Inner, kmlBoundary{Coordinates: formatKMLCoordinates(hole)})
+		}
+	} else {
+		p.Point = &kmlPoint{Coordinates: formatKMLCoordinates([][2]float64{{in.Longitude, in.Latitude}})}
+		p.Data = []kmlData{{Name: "radius_m", Value: strconv.Itoa(in.RadiusM)}}
+	}
+	return e.enc.Encode(p)
+}
+
+func (e *kmlEncoder) End() error {
+	_, err := io.WriteString(e.w, "</Document></kml>\n")
+	return err
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"geo-notifications/internal/auth"
+)
+
+// AuthMiddleware проверяет API-ключ или JWT и право роли на маршрут из Routes.
+// Запросы к неизвестным маршрутам пропускаются дальше — на них ответит
+// роутер или хендлер (404/405).
+func (h *Handler) AuthMiddleware(next http.Handler) http.Handler {
+	routes := h.Routes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := matchRoute(routes, r.Method, r.URL.Path)
+		if !ok || rt.Perm == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := h.authenticate(r)
+		if err != nil {
+			if err == errNoCredentials {
+				w.Header().Set("WWW-Authenticate", `Bearer, ApiKey`)
+				h.writeProblem(w, r, http.StatusUnauthorized, "missing_credentials", "API key or bearer token is required")
+				return
+			}
+			h.writeError(w, r, err, "failed to authenticate request")
+			return
+		}
+
+		// клиент известен и при отказе по роли — пусть попадёт в access-лог
+		ctx := setAccessPrincipal(r.Context(), principal)
+		if !principal.Role.Can(rt.Perm) {
+			h.writeProblem(w, r, http.StatusForbidden, "forbidden",
+				"role "+string(principal.Role)+" is not allowed to "+string(rt.Perm))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(ctx, principal)))
+	})
+}
+
+var errNoCredentials = errors.New("no credentials")
+
+// authenticate проверяет bearer-токен (JWT пользователя) или API-ключ.
+func (h *Handler) authenticate(r *http.Request) (*auth.Principal, error) {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return h.service.AuthenticateBearerToken(r.Context(), strings.TrimSpace(token))
+	}
+	if key := apiKeyFromRequest(r); key != "" {
+		return h.service.AuthenticateAPIKey(r.Context(), key)
+	}
+	return nil, errNoCredentials
+}
+
+// clientID — идентификатор клиента запроса для лимитов и ключей
+// идемпотентности: API-ключ или пользователь JWT, без аутентификации — IP.
+func clientID(r *http.Request) string {
+	if id := auth.PrincipalFromContext(r.Context()).ClientID(); id != "" {
+		return id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// apiKeyFromRequest достаёт ключ из X-API-Key, Authorization: ApiKey <key>
+// или query-параметра api_key (EventSource в браузере не умеет заголовки).
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "ApiKey") {
+		return strings.TrimSpace(key)
+	}
+	return r.URL.Query().Get("api_key")
+}
@@ -0,0 +1,493 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/metrics"
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setTestPrincipals(svc *fakeIncidentService) {
+	svc.principals = map[string]*auth.Principal{
+		"admin-key":       {KeyID: 1, Name: "admin", Role: auth.RoleAdmin},
+		"dispatcher-key":  {KeyID: 2, Name: "dispatch", Role: auth.RoleDispatcher},
+		"reporter-key":    {KeyID: 3, Name: "app", Role: auth.RoleReporter},
+		"viewer-key":      {KeyID: 4, Name: "dashboard", Role: auth.RoleViewer},
+		"Bearer user-77":  {Name: "77", Subject: "77", Role: auth.RoleReporter},
+		"Bearer user-bad": {Name: "alice", Subject: "alice", Role: auth.RoleReporter},
+	}
+}
+
+func newAuthTestServer(svc *fakeIncidentService) http.Handler {
+	setTestPrincipals(svc)
+	h := NewHandler(logrus.New(), svc, 5)
+	return h.AuthMiddleware(NewRouter(h))
+}
+
+func TestAuthMiddleware_RolePermissions(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"missing key", "", http.MethodGet, "/api/v1/incidents", "", http.StatusUnauthorized},
+		{"unknown key", "nope", http.MethodGet, "/api/v1/incidents", "", http.StatusUnauthorized},
+		{"dispatcher creates", "dispatcher-key", http.MethodPost, "/api/v1/incidents", `{"title":"t"}`, http.StatusCreated},
+		{"reporter cannot create", "reporter-key", http.MethodPost, "/api/v1/incidents", `{"title":"t"}`, http.StatusForbidden},
+		{"viewer cannot create", "viewer-key", http.MethodPost, "/api/v1/incidents", `{"title":"t"}`, http.StatusForbidden},
+		{"viewer lists", "viewer-key", http.MethodGet, "/api/v1/incidents", "", http.StatusOK},
+		{"viewer reads stats", "viewer-key", http.MethodGet, "/api/v1/incidents/stats", "", http.StatusOK},
+		{"reporter cannot read stats", "reporter-key", http.MethodGet, "/api/v1/incidents/stats", "", http.StatusForbidden},
+		{"reporter checks location", "reporter-key", http.MethodPost, "/api/v1/location/check", `{"user_id":1}`, http.StatusOK},
+		{"dispatcher cannot check location", "dispatcher-key", http.MethodPost, "/api/v1/location/check", `{"user_id":1}`, http.StatusForbidden},
+		{"dispatcher cannot manage keys", "dispatcher-key", http.MethodGet, "/api/v1/api-keys", "", http.StatusForbidden},
+		{"admin manages keys", "admin-key", http.MethodGet, "/api/v1/api-keys", "", http.StatusOK},
+		{"viewer reads trash", "viewer-key", http.MethodGet, "/api/v1/incidents/trash", "", http.StatusOK},
+		{"dispatcher reactivates", "dispatcher-key", http.MethodPost, "/api/v1/incidents/7:reactivate", "", http.StatusNotFound},
+		{"dispatcher cannot purge", "dispatcher-key", http.MethodPost, "/api/v1/incidents/7:purge", "", http.StatusForbidden},
+		{"admin purges", "admin-key", http.MethodPost, "/api/v1/incidents/7:purge", "", http.StatusNotFound},
+		{"health is public", "", http.MethodGet, "/api/v1/system/health", "", http.StatusOK},
+		{"openapi is public", "", http.MethodGet, "/api/v1/openapi.json", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newAuthTestServer(&fakeIncidentService{})
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			srv.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d, body=%s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_AuthorizationHeaderAndQuery(t *testing.T) {
+	srv := newAuthTestServer(&fakeIncidentService{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/incidents", nil)
+	req.Header.Set("Authorization", "ApiKey viewer-key")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Authorization header: expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/events?api_key=viewer-key", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("api_key query: expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestAPIKeysHandler_Create(t *testing.T) {
+	svc := &fakeIncidentService{}
+	srv := newAuthTestServer(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/api-keys", strings.NewReader(`{"name":"console","role":"dispatcher"}`))
+	req.Header.Set("X-API-Key", "admin-key")
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var key model.APIKey
+	if err := json.NewDecoder(w.Body).Decode(&key); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if key.Key == "" || key.Role != "dispatcher" || key.Name != "console" {
+		t.Fatalf("unexpected api key: %+v", key)
+	}
+}
+
+func TestAPIKeyByIDHandler_RevokeUnknown(t *testing.T) {
+	srv := newAuthTestServer(&fakeIncidentService{})
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/api-keys/99", nil)
+	req.Header.Set("X-API-Key", "admin-key")
+	w := httptest.NewRecorder()
+
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestLocationHandler_UserIDFromBearerSubject(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		want       int
+		wantUserID int64
+	}{
+		{"derived from subject", "user-77", `{"latitude":1,"longitude":2}`, http.StatusOK, 77},
+		{"matching user_id", "user-77", `{"user_id":77}`, http.StatusOK, 77},
+		{"mismatching user_id", "user-77", `{"user_id":5}`, http.StatusForbidden, 0},
+		{"non-numeric subject", "user-bad", `{}`, http.StatusForbidden, 0},
+		{"unknown token", "forged", `{"user_id":77}`, http.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeIncidentService{}
+			srv := newAuthTestServer(svc)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/location/check", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			srv.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected status %d, got %d, body=%s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.wantUserID != 0 && (svc.checkedLocation == nil || svc.checkedLocation.UserID != tt.wantUserID) {
+				t.Fatalf("expected service to check user %d, got %+v", tt.wantUserID, svc.checkedLocation)
+			}
+			if tt.wantUserID == 0 && svc.checkedLocation != nil {
+				t.Fatalf("service must not be called, got %+v", svc.checkedLocation)
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	svc := &fakeIncidentService{}
+	setTestPrincipals(svc)
+	h := NewHandler(logrus.New(), svc, 5)
+	srv := h.AuthMiddleware(h.RateLimitMiddleware(NewRouter(h)))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/location/check", strings.NewReader(`{"user_id":1}`))
+		req.Header.Set("X-API-Key", "reporter-key")
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d, body=%s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-RateLimit-Limit"); got != "10" {
+		t.Fatalf("expected X-RateLimit-Limit 10, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "9" {
+		t.Fatalf("expected X-RateLimit-Remaining 9, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Reset"); got != "6" {
+		t.Fatalf("expected X-RateLimit-Reset 6, got %q", got)
+	}
+	if len(svc.limitedRoutes) != 1 || svc.limitedRoutes[0] != "/api/v1/location/check" {
+		t.Fatalf("expected limit of location check route, got %v", svc.limitedRoutes)
+	}
+
+	// лимит ключа исчерпан
+	svc.rateLimited = map[string]bool{"key:3": true}
+	svc.checkedLocation = nil
+	w = send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("expected Retry-After 2, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Fatalf("expected X-RateLimit-Remaining 0, got %q", got)
+	}
+	var p problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || p.Code != "rate_limited" {
+		t.Fatalf("expected rate_limited problem, got %s", w.Body.String())
+	}
+	if svc.checkedLocation != nil {
+		t.Fatalf("limited request must not reach the service")
+	}
+}
+
+func TestLocationHandler_UserRateLimited(t *testing.T) {
+	svc := &fakeIncidentService{
+		checkErr: service.NewRateLimitError(&service.RateLimitStatus{Limit: 30, RetryAfter: 2 * time.Second, Reset: time.Minute}),
+	}
+	srv := newAuthTestServer(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/location/check", strings.NewReader(`{"user_id":1}`))
+	req.Header.Set("X-API-Key", "reporter-key")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("expected Retry-After 2, got %q", got)
+	}
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	svc := &fakeIncidentService{}
+	setTestPrincipals(svc)
+	h := NewHandler(logrus.New(), svc, 5)
+	srv := h.AuthMiddleware(h.IdempotencyMiddleware(NewRouter(h)))
+
+	create := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents", strings.NewReader(body))
+		req.Header.Set("X-API-Key", "dispatcher-key")
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	first := create("retry-1", `{"title":"Fire","radius_m":10}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d, body=%s", http.StatusCreated, first.Code, first.Body.String())
+	}
+
+	replay := create("retry-1", `{"title":"Fire","radius_m":10}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Fatalf("expected replay of first response, got %d %s", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected Idempotent-Replayed header")
+	}
+	if got := replay.Header().Get("Content-Type"); got != first.Header().Get("Content-Type") {
+		t.Fatalf("expected replayed Content-Type %q, got %q", first.Header().Get("Content-Type"), got)
+	}
+	if svc.createCalls != 1 {
+		t.Fatalf("expected incident to be created once, got %d", svc.createCalls)
+	}
+
+	conflict := create("retry-1", `{"title":"Flood","radius_m":10}`)
+	if conflict.Code != http.StatusConflict {
+		t.Fatalf("expected status %d for reused key, got %d", http.StatusConflict, conflict.Code)
+	}
+	var p problem
+	if err := json.Unmarshal(conflict.Body.Bytes(), &p); err != nil || p.Code != "idempotency_key_reused" {
+		t.Fatalf("expected idempotency_key_reused problem, got %s", conflict.Body.String())
+	}
+
+	// ответ 500 не сохраняется — повтор выполняется заново
+	svc.createErr = errors.New("db is down")
+	if w := create("retry-2", `{"title":"Storm"}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	svc.createErr = nil
+	if w := create("retry-2", `{"title":"Storm"}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected fresh execution after failure, got %d", w.Code)
+	}
+	if svc.createCalls != 3 {
+		t.Fatalf("expected 3 create calls, got %d", svc.createCalls)
+	}
+	// импорт потоковый: ключ игнорируется, и тело больше maxIdempotentBody принимается
+	var features []string
+	description := strings.Repeat("x", 1024)
+	for i := 0; len(features)*len(description) <= maxIdempotentBody; i++ {
+		features = append(features, fmt.Sprintf(
+			`{"type":"Feature","geometry":{"type":"Point","coordinates":[49.1,55.8]},"properties":{"title":"t%d","description":%q,"radius_m":10}}`,
+			i, description))
+	}
+	body := `{"type":"FeatureCollection","features":[` + strings.Join(features, ",") + `]}`
+	keys := len(svc.idempotency)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents:import", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "dispatcher-key")
+	req.Header.Set("Idempotency-Key", "import-1")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected large import to be accepted, got %d: %.200s", w.Code, w.Body.String())
+	}
+	if len(svc.idempotency) != keys {
+		t.Fatalf("import must not reserve idempotency keys")
+	}
+}
+
+func TestMetricsMiddleware_CountsByRouteTemplate(t *testing.T) {
+	svc := &fakeIncidentService{}
+	setTestPrincipals(svc)
+	h := NewHandler(logrus.New(), svc, 5)
+	srv := h.MetricsMiddleware(h.AuthMiddleware(NewRouter(h)))
+
+	count := func(method, route, status string) float64 {
+		return testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(method, route, status))
+	}
+	before := map[string]float64{
+		"byID":      count(http.MethodGet, "/api/v1/incidents/{id}", "404"),
+		"noKey":     count(http.MethodGet, "/api/v1/incidents", "401"),
+		"unmatched": count(http.MethodGet, "unmatched", "404"),
+	}
+
+	for _, tt := range []struct{ key, path string }{
+		{"viewer-key", "/api/v1/incidents/7"},
+		{"viewer-key", "/api/v1/incidents/8"},
+		{"", "/api/v1/incidents"},
+		{"viewer-key", "/nope"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("X-API-Key", tt.key)
+		}
+		srv.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if d := count(http.MethodGet, "/api/v1/incidents/{id}", "404") - before["byID"]; d != 2 {
+		t.Fatalf("expected 2 requests for /api/v1/incidents/{id}, got %v", d)
+	}
+	if d := count(http.MethodGet, "/api/v1/incidents", "401") - before["noKey"]; d != 1 {
+		t.Fatalf("expected 1 unauthorized request, got %v", d)
+	}
+	if d := count(http.MethodGet, "unmatched", "404") - before["unmatched"]; d != 1 {
+		t.Fatalf("expected 1 unmatched request, got %v", d)
+	}
+
+	// SSE и WebSocket требуют Flush и Hijack от обёртки
+	var w http.ResponseWriter = &statusWriter{ResponseWriter: httptest.NewRecorder()}
+	if _, ok := w.(http.Flusher); !ok {
+		t.Fatalf("statusWriter must implement http.Flusher")
+	}
+	if _, ok := w.(http.Hijacker); !ok {
+		t.Fatalf("statusWriter must implement http.Hijacker")
+	}
+}
+
+func TestTracingMiddleware_ContinuesTraceparent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	svc := &fakeIncidentService{}
+	h := NewHandler(logrus.New(), svc, 5)
+	srv := h.TracingMiddleware(NewRouter(h))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/incidents/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v1/incidents/{id}" {
+		t.Fatalf("unexpected span name %q", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("span did not continue the incoming trace, got trace id %s", got)
+	}
+	if got := span.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected parent span id %s", got)
+	}
+}
+
+func TestRequestLogMiddleware_RequestIDAndAccessLog(t *testing.T) {
+	var buf strings.Builder
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(&buf)
+
+	svc := &fakeIncidentService{}
+	setTestPrincipals(svc)
+	h := NewHandler(logger, svc, 5)
+	srv := h.RequestLogMiddleware(h.AuthMiddleware(NewRouter(h)))
+
+	readLines := func() []map[string]any {
+		var lines []map[string]any
+		for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var m map[string]any
+			if err := json.Unmarshal([]byte(l), &m); err != nil {
+				t.Fatalf("log line is not JSON: %q", l)
+			}
+			lines = append(lines, m)
+		}
+		buf.Reset()
+		return lines
+	}
+
+	// переданный клиентом id возвращается и попадает во все строки запроса
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/incidents", strings.NewReader("{"))
+	req.Header.Set("X-API-Key", "dispatcher-key")
+	req.Header.Set("X-Request-ID", "req-42")
+	rr := httptest.NewRecorder()
+	srv.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-Request-ID"); got != "req-42" {
+		t.Fatalf("expected X-Request-ID to be echoed, got %q", got)
+	}
+	lines := readLines()
+	if len(lines) != 2 {
+		t.Fatalf("expected handler line and access line, got %d lines", len(lines))
+	}
+	if lines[0]["request_id"] != "req-42" || lines[0]["client"] != "key:2" {
+		t.Fatalf("handler log line lacks request fields: %v", lines[0])
+	}
+	access := lines[1]
+	if access["msg"] != "request" || access["request_id"] != "req-42" ||
+		access["method"] != "POST" || access["route"] != "/api/v1/incidents" ||
+		access["status"] != float64(http.StatusBadRequest) || access["client"] != "key:2" || access["user_id"] != "2" {
+		t.Fatalf("unexpected access log line: %v", access)
+	}
+	if access["bytes"] != float64(rr.Body.Len()) {
+		t.Fatalf("expected bytes %d, got %v", rr.Body.Len(), access["bytes"])
+	}
+	if _, ok := access["duration_ms"]; !ok {
+		t.Fatalf("access log line has no duration_ms")
+	}
+
+	// без заголовка или с недопустимым значением id генерируется
+	for _, incoming := range []string{"", "has space", strings.Repeat("x", 129)} {
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/incidents/7", nil)
+		req.Header.Set("Authorization", "Bearer user-77")
+		if incoming != "" {
+			req.Header.Set("X-Request-ID", incoming)
+		}
+		rr = httptest.NewRecorder()
+		srv.ServeHTTP(rr, req)
+
+		id := rr.Header().Get("X-Request-ID")
+		if len(id) != 32 || id == incoming {
+			t.Fatalf("expected generated request id for %q, got %q", incoming, id)
+		}
+		access = readLines()[0]
+		// роль reporter не читает инциденты, но пользователь уже известен
+		if access["request_id"] != id || access["user_id"] != "77" || access["status"] != float64(http.StatusForbidden) ||
+			access["route"] != "/api/v1/incidents/{id}" || access["path"] != "/api/v1/incidents/7" {
+			t.Fatalf("unexpected access log line: %v", access)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPIDoc(t *testing.T, h *Handler) openAPIDoc {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	h.OpenAPIHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPISpec_CoversRoutes(t *testing.T) {
+	h := NewHandler(logrus.New(), &fakeIncidentService{}, 5)
+	doc := loadOpenAPIDoc(t, h)
+
+	for _, rt := range h.Routes() {
+		ops, ok := doc.Paths[rt.Path]
+		if !ok {
+			t.Errorf("path %s is missing from openapi.json", rt.Path)
+			continue
+		}
+		raw, ok := ops[strings.ToLower(rt.Method)]
+		if !ok {
+			t.Errorf("operation %s %s is missing from openapi.json", rt.Method, rt.Path)
+			continue
+		}
+
+		var op struct {
+			Security  *[]json.RawMessage         `json:"security"`
+			Responses map[string]json.RawMessage `json:"responses"`
+		}
+		if err := json.Unmarshal(raw, &op); err != nil {
+			t.Fatalf("failed to decode operation %s %s: %v", rt.Method, rt.Path, err)
+		}
+		public := op.Security != nil && len(*op.Security) == 0
+		if rt.Perm == "" && !public {
+			t.Errorf("public operation %s %s must declare empty security", rt.Method, rt.Path)
+		}
+		if rt.Perm != "" {
+			if public {
+				t.Errorf("operation %s %s requires %s but is documented as public", rt.Method, rt.Path, rt.Perm)
+			}
+			for _, code := range []string{"401", "403"} {
+				if _, ok := op.Responses[code]; !ok {
+					t.Errorf("operation %s %s must document %s response", rt.Method, rt.Path, code)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPISpec_CoversModelFields(t *testing.T) {
+	h := NewHandler(logrus.New(), &fakeIncidentService{}, 5)
+	doc := loadOpenAPIDoc(t, h)
+
+	schemas := map[string]reflect.Type{
+		"Incident":             reflect.TypeOf(model.Incident{}),
+		"ImportResult":         reflect.TypeOf(model.ImportResult{}),
+		"LocationRequest":      reflect.TypeOf(model.LocationRequest{}),
+		"LocationResponse":     reflect.TypeOf(model.LocationResponse{}),
+		"WebhookPayload":       reflect.TypeOf(model.WebhookPayload{}),
+		"Event":                reflect.TypeOf(model.Event{}),
+		"Alert":                reflect.TypeOf(model.Alert{}),
+		"Problem":              reflect.TypeOf(problem{}),
+		"FieldError":           reflect.TypeOf(service.FieldError{}),
+		"APIKey":               reflect.TypeOf(model.APIKey{}),
+		"IncidentChange":       reflect.TypeOf(model.IncidentChange{}),
+		"FieldChange":          reflect.TypeOf(model.FieldChange{}),
+		"IncidentsStats":       reflect.TypeOf(model.IncidentsStats{}),
+		"IncidentMatchStats":   reflect.TypeOf(model.IncidentMatchStats{}),
+		"StatsPoint":           reflect.TypeOf(model.StatsPoint{}),
+		"IncidentStats":        reflect.TypeOf(model.IncidentStats{}),
+		"ReachPoint":           reflect.TypeOf(model.ReachPoint{}),
+		"WebhookDeliveryStats": reflect.TypeOf(model.WebhookDeliveryStats{}),
+		"Readiness":            reflect.TypeOf(model.Readiness{}),
+		"DependencyCheck":      reflect.TypeOf(model.DependencyCheck{}),
+	}
+
+	for name, typ := range schemas {
+		schema, ok := doc.Components.Schemas[name]
+		if !ok {
+			t.Errorf("schema %s is missing from openapi.json", name)
+			continue
+		}
+		for _, field := range jsonFieldNames(typ) {
+			if _, ok := schema.Properties[field]; !ok {
+				t.Errorf("field %s.%s is missing from openapi.json", name, field)
+			}
+		}
+	}
+}
+
+func TestNewRouter_RegistersRoutes(t *testing.T) {
+	h := NewHandler(logrus.New(), &fakeIncidentService{}, 5)
+	mux := NewRouter(h)
+
+	for _, rt := range h.Routes() {
+		path := strings.ReplaceAll(rt.Path, "{id}", "1")
+		req := httptest.NewRequest(rt.Method, path, nil)
+		if _, pattern := mux.Handler(req); pattern != muxPattern(rt.Path) {
+			t.Errorf("route %s %s is served by %q", rt.Method, rt.Path, pattern)
+		}
+	}
+}
+
+// jsonFieldNames возвращает имена полей в JSON с учётом встроенных структур.
+func jsonFieldNames(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" {
+			names = append(names, jsonFieldNames(f.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"geo-notifications/internal/model"
+)
+
+// probePaths не ограничиваются лимитами: kubelet опрашивает их с одного
+// адреса, и 429 выглядел бы для него как отказ пода.
+var probePaths = map[string]bool{"/livez": true, "/readyz": true}
+
+// LivezHandler отвечает 200, пока процесс обслуживает запросы. Зависимости
+// не проверяются: их сбой — повод вывести под из балансировки, а не
+// перезапускать его.
+func (h *Handler) LivezHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+	writeProbe(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler отвечает 503, если недоступен Postgres или Redis или
+// сервер начал остановку.
+func (h *Handler) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+	readiness := h.service.Readiness(r.Context())
+	status := http.StatusOK
+	if readiness.Status != model.ReadinessReady {
+		status = http.StatusServiceUnavailable
+	}
+	writeProbe(w, status, readiness)
+}
+
+func writeProbe(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"geo-notifications/internal/service"
+)
+
+// RateLimitMiddleware ограничивает запросы клиента к маршруту. Клиент —
+// API-ключ или пользователь JWT, без аутентификации — IP. Должен стоять
+// после AuthMiddleware, чтобы клиент был уже известен.
+func (h *Handler) RateLimitMiddleware(next http.Handler) http.Handler {
+	routes := h.Routes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := matchRoute(routes, r.Method, r.URL.Path)
+		if !ok || probePaths[rt.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		st, err := h.service.AllowRequest(r.Context(), rt.Path, clientID(r))
+		if err != nil {
+			h.writeError(w, r, err, "failed to check rate limit")
+			return
+		}
+		if st != nil {
+			setRateLimitHeaders(w, st)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, st *service.RateLimitStatus) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(st.Limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(st.Remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(st.Reset)))
+	if st.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(st.RetryAfter)))
+	}
+}
+
+// ceilSeconds округляет вверх: Retry-After: 0 при пустом бакете лишь
+// спровоцирует повтор раньше времени.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/logging"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDMaxLen ограничивает чужой X-Request-ID, чтобы он не раздувал логи.
+const requestIDMaxLen = 128
+
+// accessInfo заполняют внутренние middleware: AuthMiddleware кладёт сюда
+// principal, чтобы он попал в строку access-лога.
+type accessInfo struct {
+	principal *auth.Principal
+}
+
+type accessInfoKey struct{}
+
+// RequestLogMiddleware берёт X-Request-ID клиента (или выдаёт новый) и
+// возвращает его в ответе, кладёт в контекст логгер с request_id для
+// хендлеров и сервиса и пишет одну строку access-лога на запрос.
+func (h *Handler) RequestLogMiddleware(next http.Handler) http.Handler {
+	routes := h.Routes()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		entry := h.log(r).WithField("request_id", id)
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			entry = entry.WithField("trace_id", sc.TraceID().String())
+		}
+		info := &accessInfo{}
+		ctx := logging.WithEntry(r.Context(), entry)
+		ctx = context.WithValue(ctx, accessInfoKey{}, info)
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		route := "unmatched"
+		if rt, ok := matchRoute(routes, r.Method, r.URL.Path); ok {
+			route = rt.Path
+		}
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		fields := logrus.Fields{
+			"method":      r.Method,
+			"route":       route,
+			"path":        r.URL.Path,
+			"status":      status,
+			"bytes":       sw.bytes,
+			"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if p := info.principal; p != nil {
+			fields["client"] = p.ClientID()
+			fields["tenant"] = p.Tenant
+			fields["user_id"] = accessUserID(p)
+		}
+		entry.WithFields(fields).Info("request")
+	})
+}
+
+// accessUserID — кто сделал запрос: id API-ключа, sub JWT или, для ключа
+// без id в базе (ADMIN_API_KEY), его имя.
+func accessUserID(p *auth.Principal) string {
+	switch {
+	case p.KeyID > 0:
+		return strconv.FormatInt(p.KeyID, 10)
+	case p.Subject != "":
+		return p.Subject
+	default:
+		return p.Name
+	}
+}
+
+// setAccessPrincipal запоминает principal для access-лога и добавляет
+// client в логгер запроса.
+func setAccessPrincipal(ctx context.Context, p *auth.Principal) context.Context {
+	if info, ok := ctx.Value(accessInfoKey{}).(*accessInfo); ok {
+		info.principal = p
+	}
+	if entry, ok := logging.EntryFromContext(ctx); ok {
+		ctx = logging.WithEntry(ctx, entry.WithField("client", p.ClientID()))
+	}
+	return ctx
+}
+
+// log — логгер запроса с request_id, если запрос прошёл RequestLogMiddleware.
+func (h *Handler) log(r *http.Request) *logrus.Entry {
+	return logging.FromContext(r.Context(), h.logger)
+}
+
+// validRequestID пропускает только печатные ASCII-символы без пробелов.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"geo-notifications/internal/auth"
+)
+
+// Route — эндпоинт API. Path записан в формате OpenAPI
+// (/api/v1/incidents/{id}); по нему же строится паттерн для ServeMux.
+// Perm — право, требуемое для вызова; пустое значение — публичный эндпоинт.
+type Route struct {
+	Method  string
+	Path    string
+	Handler http.HandlerFunc
+	Perm    auth.Permission
+}
+
+// Routes возвращает все эндпоинты HTTP API. Этот список — источник правды
+// для роутера, проверки прав и проверки OpenAPI-спецификации.
+func (h *Handler) Routes() []Route {
+	return []Route{
+		{http.MethodGet, "/api/v1/incidents", h.IncidentsHandler, auth.PermIncidentsRead},
+		{http.MethodPost, "/api/v1/incidents", h.IncidentsHandler, auth.PermIncidentsWrite},
+		{http.MethodPost, "/api/v1/incidents:import", h.IncidentsImportHandler, auth.PermIncidentsWrite},
+		{http.MethodGet, "/api/v1/incidents:export", h.IncidentsExportHandler, auth.PermIncidentsRead},
+		{http.MethodGet, "/api/v1/incidents/{id}", h.IncidentByIDHandler, auth.PermIncidentsRead},
+		{http.MethodPut, "/api/v1/incidents/{id}", h.IncidentByIDHandler, auth.PermIncidentsWrite},
+		{http.MethodPatch, "/api/v1/incidents/{id}", h.IncidentByIDHandler, auth.PermIncidentsWrite},
+		{http.MethodDelete, "/api/v1/incidents/{id}", h.IncidentByIDHandler, auth.PermIncidentsWrite},
+		{http.MethodGet, "/api/v1/incidents/{id}/history", h.IncidentByIDHandler, auth.PermIncidentsRead},
+		{http.MethodGet, "/api/v1/incidents/{id}/stats", h.IncidentByIDHandler, auth.PermStatsRead},
+		{http.MethodPost, "/api/v1/incidents/{id}:reactivate", h.IncidentByIDHandler, auth.PermIncidentsWrite},
+		{http.MethodPost, "/api/v1/incidents/{id}:purge", h.IncidentByIDHandler, auth.PermIncidentsPurge},
+		{http.MethodGet, "/api/v1/incidents/trash", h.IncidentsTrashHandler, auth.PermIncidentsRead},
+		{http.MethodGet, "/api/v1/incidents/stats", h.IncidentsStatsHandler, auth.PermStatsRead},
+		{http.MethodPost, "/api/v1/location/check", h.LocationHandler, auth.PermLocationCheck},
+		{http.MethodGet, "/api/v1/location/heatmap", h.HeatmapHandler, auth.PermStatsRead},
+		{http.MethodGet, "/api/v1/events", h.EventsHandler, auth.PermEventsRead},
+		{http.MethodGet, "/api/v1/api-keys", h.APIKeysHandler, auth.PermAPIKeysManage},
+		{http.MethodPost, "/api/v1/api-keys", h.APIKeysHandler, auth.PermAPIKeysManage},
+		{http.MethodDelete, "/api/v1/api-keys/{id}", h.APIKeyByIDHandler, auth.PermAPIKeysManage},
+		// алерты защищены собственным токеном пользователя
+		{http.MethodGet, "/api/v1/alerts/ws", h.AlertsWSHandler, ""},
+		{http.MethodGet, "/api/v1/system/health", h.HealthHandler, ""},
+		{http.MethodGet, "/livez", h.LivezHandler, ""},
+		{http.MethodGet, "/readyz", h.ReadyzHandler, ""},
+		{http.MethodGet, "/api/v1/openapi.json", h.OpenAPIHandler, ""},
+	}
+}
+
+// NewRouter регистрирует Routes в ServeMux. Путь с параметрами
+// регистрируется префиксом до первого параметра, разбор параметров и
+// методов остаётся за самими хендлерами.
+func NewRouter(h *Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	registered := make(map[string]bool)
+	for _, rt := range h.Routes() {
+		pattern := muxPattern(rt.Path)
+		if registered[pattern] {
+			continue
+		}
+		registered[pattern] = true
+		mux.HandleFunc(pattern, rt.Handler)
+	}
+	return mux
+}
+
+func muxPattern(path string) string {
+	if i := strings.Index(path, "{"); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// matchRoute находит маршрут для метода и пути запроса. Литеральные
+// сегменты приоритетнее параметров (/incidents/stats, а не /incidents/{id}).
+func matchRoute(routes []Route, method, path string) (Route, bool) {
+	reqParts := strings.Split(strings.Trim(path, "/"), "/")
+
+	var best Route
+	bestParams := -1
+	for _, rt := range routes {
+		if rt.Method != method {
+			continue
+		}
+		params, ok := matchPath(strings.Split(strings.Trim(rt.Path, "/"), "/"), reqParts)
+		if !ok {
+			continue
+		}
+		if bestParams == -1 || params < bestParams {
+			best, bestParams = rt, params
+		}
+	}
+	return best, bestParams != -1
+}
+
+func matchPath(tmpl, parts []string) (params int, ok bool) {
+	if len(tmpl) != len(parts) {
+		return 0, false
+	}
+	for i, seg := range tmpl {
+		if strings.HasPrefix(seg, "{") {
+			// параметр, возможно с кастомным методом: {id}:reactivate
+			_, method, _ := strings.Cut(seg, "}")
+			value, ok := strings.CutSuffix(parts[i], method)
+			if !ok || value == "" || strings.Contains(value, ":") {
+				return 0, false
+			}
+			params++
+			continue
+		}
+		if seg != parts[i] {
+			return 0, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// TracingMiddleware открывает span на запрос и продолжает трассу из
+// входящего traceparent. Span назван по маршруту из Routes
+// ("GET /api/v1/incidents/{id}"), чтобы id не попадали в имена.
+func (h *Handler) TracingMiddleware(next http.Handler) http.Handler {
+	routes := h.Routes()
+
+	return otelhttp.NewHandler(next, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if rt, ok := matchRoute(routes, r.Method, r.URL.Path); ok {
+				return r.Method + " " + rt.Path
+			}
+			return r.Method + " unmatched"
+		}),
+	)
+}
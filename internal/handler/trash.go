@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"geo-notifications/internal/model"
+)
+
+// POST /api/v1/incidents/{id}:reactivate и /api/v1/incidents/{id}:purge
+func (h *Handler) incidentAction(w http.ResponseWriter, r *http.Request, id int64, action string) {
+	if action != "reactivate" && action != "purge" {
+		h.writeProblem(w, r, http.StatusNotFound, "not_found", "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	if action == "purge" {
+		if err := h.service.PurgeIncident(r.Context(), id); err != nil {
+			h.writeError(w, r, err, "error purging incident")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	incident, err := h.service.ReactivateIncident(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, err, "error reactivating incident")
+		return
+	}
+
+	w.Header().Set("ETag", incidentETag(incident))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(incident)
+}
+
+// GET /api/v1/incidents/trash — деактивированные инциденты
+func (h *Handler) IncidentsTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	page, pageSize, ok := h.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.service.GetTrash(r.Context(), page, pageSize)
+	if err != nil {
+		h.writeError(w, r, err, "error while getting trash")
+		return
+	}
+
+	resp := struct {
+		Items    []model.Incident `json:"items"`
+		Page     int              `json:"page"`
+		PageSize int              `json:"page_size"`
+	}{
+		Items:    items,
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
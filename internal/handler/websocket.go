@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/service"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+// SetAlertsOrigins задаёт origin'ы веб-клиентов (схема://хост[:порт]),
+// которым разрешено открывать WebSocket-алерты помимо своего хоста.
+func (h *Handler) SetAlertsOrigins(origins []string) {
+	h.alertsOrigins = origins
+}
+
+// checkAlertsOrigin защищает от чужих страниц, открывающих сокет с токеном
+// из браузера пользователя. Без Origin приходят не браузеры — их пускаем.
+func (h *Handler) checkAlertsOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range h.alertsOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// GET /api/v1/alerts/ws?user_id=...&token=...[&tenant=...] (WebSocket)
+func (h *Handler) AlertsWSHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.methodNotAllowed(w, r)
+		return
+	}
+
+	if !h.checkAlertsOrigin(r) {
+		h.writeProblem(w, r, http.StatusForbidden, "origin_not_allowed", "origin is not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	userID, err := strconv.ParseInt(q.Get("user_id"), 10, 64)
+	if err != nil || userID <= 0 {
+		h.writeProblem(w, r, http.StatusBadRequest, "invalid_parameter", "invalid user_id parameter",
+			service.FieldError{Field: "user_id", Message: "must be a positive integer"})
+		return
+	}
+
+	tenant := q.Get("tenant")
+	if tenant == "" {
+		tenant = auth.DefaultTenant
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	alerts, err := h.service.SubscribeUserAlerts(ctx, tenant, userID, q.Get("token"))
+	if err != nil {
+		h.writeError(w, r, err, "failed to subscribe to user alerts")
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     h.checkAlertsOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade сам отвечает клиенту ошибкой
+		h.log(r).WithError(err).Info("websocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	// читаем входящие кадры только ради pong/close; по обрыву гасим подписку
+	go func() {
+		defer cancel()
+		conn.SetReadLimit(512)
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case alert, ok := <-alerts:
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				_ = conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "stream closed"))
+				return
+			}
+			if err := conn.WriteJSON(alert); err != nil {
+				h.log(r).WithError(err).Info("failed to write websocket message")
+				return
+			}
+		}
+	}
+}
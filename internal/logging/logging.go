@@ -0,0 +1,32 @@
+// Package logging передаёт логгер запроса через context: в нём уже есть
+// request_id и другие поля запроса, так что записи хендлеров и сервиса
+// связываются с запросом, который их вызвал.
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type entryKey struct{}
+
+// WithEntry кладёт логгер запроса в ctx.
+func WithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// EntryFromContext возвращает логгер запроса, если он есть в ctx.
+func EntryFromContext(ctx context.Context) (*logrus.Entry, bool) {
+	entry, ok := ctx.Value(entryKey{}).(*logrus.Entry)
+	return entry, ok
+}
+
+// FromContext возвращает логгер запроса из ctx, а вне запроса — logger
+// без дополнительных полей.
+func FromContext(ctx context.Context, logger *logrus.Logger) *logrus.Entry {
+	if entry, ok := EntryFromContext(ctx); ok {
+		return entry
+	}
+	return logrus.NewEntry(logger)
+}
@@ -0,0 +1,70 @@
+// Package metrics — метрики Prometheus сервиса. Счётчики и гистограммы
+// обновляют слои handler, service и воркер вебхуков; состояние базы и
+// Redis снимает коллектор repository при каждом опросе.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "geo"
+
+// Registry — реестр метрик сервиса, отдаётся на /metrics.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	// route — шаблон пути из Routes (/api/v1/incidents/{id}), чтобы id
+	// не плодили ряды; запросы мимо маршрутов идут с route="unmatched"
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// result: match — пользователь попал хотя бы в один инцидент, miss — ни в один
+	LocationChecks = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "location_checks_total",
+		Help:      "Location checks by result (match or miss).",
+	}, []string{"result"})
+
+	// result: success — ответ 2xx, failure — другой статус или ошибка отправки
+	WebhookDeliveries = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by result (success or failure).",
+	}, []string{"result"})
+
+	WebhookDeliveryDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_duration_seconds",
+		Help:      "Webhook delivery latency including failed attempts.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler отдаёт метрики в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
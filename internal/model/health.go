@@ -0,0 +1,28 @@
+package model
+
+// состояние готовности
+const (
+	ReadinessReady        = "ready"
+	ReadinessNotReady     = "not_ready"
+	ReadinessShuttingDown = "shutting_down"
+)
+
+// Readiness — ответ /readyz. Готовность определяют только Postgres, Redis
+// и остановка сервера; очередь и воркер показываются для диагностики.
+type Readiness struct {
+	Status string `json:"status"`
+	// ключи — postgres и redis
+	Checks map[string]DependencyCheck `json:"checks"`
+	// задачи во всех очередях вебхуков; nil, если Redis недоступен
+	WebhookQueueDepth *int64 `json:"webhook_queue_depth"`
+	// сколько секунд назад воркер вебхуков последний раз прошёл цикл;
+	// nil, если воркер ещё не запускался
+	WorkerHeartbeatAgeSeconds *float64 `json:"worker_heartbeat_age_seconds"`
+}
+
+// DependencyCheck — результат пинга зависимости. Текст ошибки не
+// отдаётся наружу (в нём адреса внутренней сети), он пишется в лог.
+type DependencyCheck struct {
+	Status    string  `json:"status"` // ok или error
+	LatencyMs float64 `json:"latency_ms"`
+}
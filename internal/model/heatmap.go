@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// виды сетки тепловой карты
+const (
+	HeatmapGeohash = "geohash"
+	HeatmapSquare  = "square"
+)
+
+// Heatmap — проверки локаций за [From, To), собранные по ячейкам сетки.
+// Ячейки, где было меньше MinUsers разных пользователей, не отдаются, а
+// только считаются в Suppressed.
+type Heatmap struct {
+	Grid       string
+	Precision  int
+	From       time.Time
+	To         time.Time
+	MinUsers   int
+	Suppressed int
+	Cells      []HeatmapCell
+}
+
+// HeatmapCell — ячейка сетки: Row и Col считаются от южного полюса и
+// антимеридиана, Bounds — minLon, minLat, maxLon, maxLat.
+type HeatmapCell struct {
+	ID     string
+	Row    int64
+	Col    int64
+	Bounds [4]float64
+	Checks int
+	Users  int
+}
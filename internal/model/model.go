@@ -1,17 +1,80 @@
 package model
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 type Incident struct {
-	ID          int64     `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Latitude    float64   `json:"latitude"`
-	Longitude   float64   `json:"longitude"`
-	RadiusM     int       `json:"radius_m"`
-	Active      bool      `json:"active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            int64      `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Latitude      float64    `json:"latitude"`
+	Longitude     float64    `json:"longitude"`
+	RadiusM       int        `json:"radius_m"`
+	Active        bool       `json:"active"`
+	Version       int64      `json:"version"`                  // растёт при каждом изменении, отдаётся как ETag
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"` // когда инцидент попал в корзину
+	Polygon       Polygon    `json:"polygon,omitempty"`        // зона инцидента вместо круга radius_m
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Covers сообщает, попадает ли точка в зону инцидента (полигон или радиус),
+// не глядя на Active.
+func (in Incident) Covers(lon, lat float64) bool {
+	if len(in.Polygon) > 0 {
+		return in.Polygon.Contains(lon, lat)
+	}
+	// Условная метрика "близости" — сейчас просто манхэттен в градусах.
+	return math.Abs(in.Latitude-lat)+math.Abs(in.Longitude-lon) <= float64(in.RadiusM)
+}
+
+// IncidentPatch — частичное обновление инцидента (JSON Merge Patch):
+// nil-поле не меняется.
+type IncidentPatch struct {
+	Title       *string
+	Description *string
+	Latitude    *float64
+	Longitude   *float64
+	RadiusM     *int
+	Active      *bool
+	// Polygon — указатель на nil удаляет полигон
+	Polygon *Polygon
+}
+
+const (
+	HistoryCreated     = "created"
+	HistoryUpdated     = "updated"
+	HistoryDeactivated = "deactivated"
+	HistoryReactivated = "reactivated"
+	HistoryPurged      = "purged"
+)
+
+// IncidentChange — неизменяемая запись истории инцидента: кто, когда и
+// какие поля поменял. Version — версия инцидента после изменения.
+type IncidentChange struct {
+	ID         int64                  `json:"id"`
+	IncidentID int64                  `json:"incident_id"`
+	Action     string                 `json:"action"`
+	Actor      string                 `json:"actor"`
+	Version    int64                  `json:"version"`
+	Changes    map[string]FieldChange `json:"changes"`
+	ChangedAt  time.Time              `json:"changed_at"`
+}
+
+// FieldChange — значение поля до и после изменения; при создании From
+// пустой.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// ImportResult — итог импорта: сколько инцидентов создано и их id в
+// порядке файла.
+type ImportResult struct {
+	Created int     `json:"created"`
+	IDs     []int64 `json:"ids"`
 }
 
 type LocationRequest struct {
@@ -31,4 +94,65 @@ type WebhookPayload struct {
 	Longitude    float64   `json:"longitude"`
 	LocationsIDS []int64   `json:"locations_ids"`
 	CheckedAt    time.Time `json:"checked_at"`
+	Tenant       string    `json:"tenant"`
+}
+
+// WebhookTask — задача в очереди вебхуков: тело вебхука и контекст
+// трассировки проверки локации (traceparent), на которую ссылается доставка.
+type WebhookTask struct {
+	WebhookPayload
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+}
+
+const (
+	EventIncidentCreated     = "incident.created"
+	EventIncidentUpdated     = "incident.updated"
+	EventIncidentDeactivated = "incident.deactivated"
+	EventIncidentReactivated = "incident.reactivated"
+	EventIncidentPurged      = "incident.purged"
+	EventLocationMatched     = "location.matched"
+)
+
+// Event — событие жизненного цикла инцидента или совпадения локации,
+// рассылаемое подписчикам через Redis pub/sub. Подписчик получает только
+// события своего тенанта.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Tenant     string          `json:"tenant"`
+	Incident   *Incident       `json:"incident,omitempty"`
+	Match      *WebhookPayload `json:"match,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// Alert — сообщение WebSocket-алертов пользователя: тело вебхука с типом
+// события. У location.matched поля вебхука — та же проверка, что уходит
+// на вебхук; у событий инцидента — последняя проверка пользователя, где
+// locations_ids уже учитывает изменение, и сам инцидент.
+type Alert struct {
+	Type string `json:"type"`
+	WebhookPayload
+	Incident *Incident `json:"incident,omitempty"`
+}
+
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	Tenant    string     `json:"tenant"`
+	Prefix    string     `json:"prefix"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Key отдаётся только один раз — в ответе на создание.
+	Key string `json:"key,omitempty"`
+}
+
+// IdempotencyRecord — первый ответ на запрос с Idempotency-Key. Пока запрос
+// выполняется, Done=false и ответа ещё нет.
+type IdempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	Done        bool              `json:"done"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
 }
@@ -0,0 +1,46 @@
+package model
+
+// Polygon — координаты полигона GeoJSON: первое кольцо — внешняя граница,
+// остальные — дыры. Точка — [долгота, широта], кольцо замкнуто (первая
+// точка совпадает с последней).
+type Polygon [][][2]float64
+
+// Contains сообщает, лежит ли точка внутри внешнего кольца и вне дыр.
+func (p Polygon) Contains(lon, lat float64) bool {
+	if len(p) == 0 || !ringContains(p[0], lon, lat) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, lon, lat) {
+			return false
+		}
+	}
+	return true
+}
+
+// Centroid — среднее вершин внешнего кольца; для подписи инцидента на
+// карте и совместимости с клиентами, которые знают только центр.
+func (p Polygon) Centroid() (lon, lat float64) {
+	if len(p) == 0 || len(p[0]) < 2 {
+		return 0, 0
+	}
+	ring := p[0][:len(p[0])-1]
+	for _, pt := range ring {
+		lon += pt[0]
+		lat += pt[1]
+	}
+	return lon / float64(len(ring)), lat / float64(len(ring))
+}
+
+// ringContains — ray casting по замкнутому кольцу.
+func ringContains(ring [][2]float64, lon, lat float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// шаг ряда статистики
+const (
+	StatsByMinute = "minute"
+	StatsByHour   = "hour"
+)
+
+// IncidentsStats — статистика проверок локаций за [From, To).
+type IncidentsStats struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Bucket string    `json:"bucket"`
+	// уникальные пользователи, у которых были совпадения
+	UserCount      int                  `json:"user_count"`
+	TotalChecks    int                  `json:"total_checks"`
+	MatchingChecks int                  `json:"matching_checks"`
+	Incidents      []IncidentMatchStats `json:"incidents"`
+	Series         []StatsPoint         `json:"series"`
+}
+
+// IncidentMatchStats — сколько проверок попало в зону инцидента и скольких
+// разных пользователей.
+type IncidentMatchStats struct {
+	IncidentID  int64 `json:"incident_id"`
+	Matches     int   `json:"matches"`
+	UniqueUsers int   `json:"unique_users"`
+}
+
+// StatsPoint — точка ряда: проверки с Start до начала следующей точки.
+type StatsPoint struct {
+	Start          time.Time `json:"start"`
+	TotalChecks    int       `json:"total_checks"`
+	MatchingChecks int       `json:"matching_checks"`
+	UniqueUsers    int       `json:"unique_users"`
+}
+
+// IncidentStats — охват одного инцидента за всё время: кого он затронул и
+// как доходили вебхуки о совпадениях с ним.
+type IncidentStats struct {
+	IncidentID   int64      `json:"incident_id"`
+	UniqueUsers  int        `json:"unique_users"`
+	Matches      int        `json:"matches"`
+	FirstMatchAt *time.Time `json:"first_match_at"`
+	LastMatchAt  *time.Time `json:"last_match_at"`
+	// по часам от первого совпадения до последнего, не длиннее 31 дня
+	Hourly   []ReachPoint         `json:"hourly"`
+	Webhooks WebhookDeliveryStats `json:"webhooks"`
+}
+
+// ReachPoint — совпадения с инцидентом за час, начиная со Start.
+type ReachPoint struct {
+	Start       time.Time `json:"start"`
+	Matches     int       `json:"matches"`
+	UniqueUsers int       `json:"unique_users"`
+}
+
+// WebhookDeliveryStats — отправки вебхуков, в которых был инцидент.
+// SuccessRate пуст, пока отправок не было.
+type WebhookDeliveryStats struct {
+	Attempts    int      `json:"attempts"`
+	Succeeded   int      `json:"succeeded"`
+	SuccessRate *float64 `json:"success_rate"`
+}
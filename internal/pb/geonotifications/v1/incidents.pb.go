@@ -0,0 +1,1629 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: geonotifications/v1/incidents.proto
+
+package geonotificationsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Incident struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title       string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Latitude    float64                `protobuf:"fixed64,4,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude   float64                `protobuf:"fixed64,5,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	RadiusM     int32                  `protobuf:"varint,6,opt,name=radius_m,json=radiusM,proto3" json:"radius_m,omitempty"`
+	Active      bool                   `protobuf:"varint,7,opt,name=active,proto3" json:"active,omitempty"`
+	CreatedAt   *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt   *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// растёт при каждом изменении инцидента
+	Version int64 `protobuf:"varint,10,opt,name=version,proto3" json:"version,omitempty"`
+	// зона вместо круга radius_m; у инцидента с полигоном latitude и
+	// longitude — центроид, radius_m — 0
+	Polygon       *Polygon `protobuf:"bytes,11,opt,name=polygon,proto3" json:"polygon,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Incident) Reset() {
+	*x = Incident{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Incident) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Incident) ProtoMessage() {}
+
+func (x *Incident) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Incident.ProtoReflect.Descriptor instead.
+func (*Incident) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Incident) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Incident) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Incident) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Incident) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Incident) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Incident) GetRadiusM() int32 {
+	if x != nil {
+		return x.RadiusM
+	}
+	return 0
+}
+
+func (x *Incident) GetActive() bool {
+	if x != nil {
+		return x.Active
+	}
+	return false
+}
+
+func (x *Incident) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Incident) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Incident) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Incident) GetPolygon() *Polygon {
+	if x != nil {
+		return x.Polygon
+	}
+	return nil
+}
+
+// Polygon — полигон GeoJSON: первое кольцо — внешняя граница, остальные —
+// дыры; кольца замкнуты (первая точка совпадает с последней).
+type Polygon struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rings         []*Ring                `protobuf:"bytes,1,rep,name=rings,proto3" json:"rings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Polygon) Reset() {
+	*x = Polygon{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Polygon) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Polygon) ProtoMessage() {}
+
+func (x *Polygon) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Polygon.ProtoReflect.Descriptor instead.
+func (*Polygon) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Polygon) GetRings() []*Ring {
+	if x != nil {
+		return x.Rings
+	}
+	return nil
+}
+
+type Ring struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Points        []*Point               `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ring) Reset() {
+	*x = Ring{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ring) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ring) ProtoMessage() {}
+
+func (x *Ring) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ring.ProtoReflect.Descriptor instead.
+func (*Ring) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Ring) GetPoints() []*Point {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+type Point struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Longitude     float64                `protobuf:"fixed64,1,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Latitude      float64                `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Point) Reset() {
+	*x = Point{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Point) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Point) ProtoMessage() {}
+
+func (x *Point) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Point.ProtoReflect.Descriptor instead.
+func (*Point) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Point) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Point) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+type CreateIncidentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Latitude      float64                `protobuf:"fixed64,3,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,4,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	RadiusM       int32                  `protobuf:"varint,5,opt,name=radius_m,json=radiusM,proto3" json:"radius_m,omitempty"`
+	Polygon       *Polygon               `protobuf:"bytes,6,opt,name=polygon,proto3" json:"polygon,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateIncidentRequest) Reset() {
+	*x = CreateIncidentRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateIncidentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateIncidentRequest) ProtoMessage() {}
+
+func (x *CreateIncidentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateIncidentRequest.ProtoReflect.Descriptor instead.
+func (*CreateIncidentRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateIncidentRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateIncidentRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateIncidentRequest) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *CreateIncidentRequest) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *CreateIncidentRequest) GetRadiusM() int32 {
+	if x != nil {
+		return x.RadiusM
+	}
+	return 0
+}
+
+func (x *CreateIncidentRequest) GetPolygon() *Polygon {
+	if x != nil {
+		return x.Polygon
+	}
+	return nil
+}
+
+type CreateIncidentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Incident      *Incident              `protobuf:"bytes,1,opt,name=incident,proto3" json:"incident,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateIncidentResponse) Reset() {
+	*x = CreateIncidentResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateIncidentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateIncidentResponse) ProtoMessage() {}
+
+func (x *CreateIncidentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateIncidentResponse.ProtoReflect.Descriptor instead.
+func (*CreateIncidentResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateIncidentResponse) GetIncident() *Incident {
+	if x != nil {
+		return x.Incident
+	}
+	return nil
+}
+
+type GetIncidentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetIncidentRequest) Reset() {
+	*x = GetIncidentRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetIncidentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIncidentRequest) ProtoMessage() {}
+
+func (x *GetIncidentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIncidentRequest.ProtoReflect.Descriptor instead.
+func (*GetIncidentRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetIncidentRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetIncidentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Incident      *Incident              `protobuf:"bytes,1,opt,name=incident,proto3" json:"incident,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetIncidentResponse) Reset() {
+	*x = GetIncidentResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetIncidentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetIncidentResponse) ProtoMessage() {}
+
+func (x *GetIncidentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetIncidentResponse.ProtoReflect.Descriptor instead.
+func (*GetIncidentResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetIncidentResponse) GetIncident() *Incident {
+	if x != nil {
+		return x.Incident
+	}
+	return nil
+}
+
+type ListIncidentsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// по умолчанию 1
+	Page int32 `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	// по умолчанию 20
+	PageSize      int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListIncidentsRequest) Reset() {
+	*x = ListIncidentsRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIncidentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIncidentsRequest) ProtoMessage() {}
+
+func (x *ListIncidentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIncidentsRequest.ProtoReflect.Descriptor instead.
+func (*ListIncidentsRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListIncidentsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListIncidentsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type ListIncidentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*Incident            `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	Page          int32                  `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	PageSize      int32                  `protobuf:"varint,3,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListIncidentsResponse) Reset() {
+	*x = ListIncidentsResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListIncidentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListIncidentsResponse) ProtoMessage() {}
+
+func (x *ListIncidentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListIncidentsResponse.ProtoReflect.Descriptor instead.
+func (*ListIncidentsResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListIncidentsResponse) GetItems() []*Incident {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *ListIncidentsResponse) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListIncidentsResponse) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+type UpdateIncidentRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// заменяет инцидент целиком: без polygon он становится кругом
+	Incident *Incident `protobuf:"bytes,1,opt,name=incident,proto3" json:"incident,omitempty"`
+	// текущая версия инцидента (аналог If-Match в HTTP), обязательна
+	ExpectedVersion int64 `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UpdateIncidentRequest) Reset() {
+	*x = UpdateIncidentRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateIncidentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateIncidentRequest) ProtoMessage() {}
+
+func (x *UpdateIncidentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateIncidentRequest.ProtoReflect.Descriptor instead.
+func (*UpdateIncidentRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpdateIncidentRequest) GetIncident() *Incident {
+	if x != nil {
+		return x.Incident
+	}
+	return nil
+}
+
+func (x *UpdateIncidentRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+type UpdateIncidentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Incident      *Incident              `protobuf:"bytes,1,opt,name=incident,proto3" json:"incident,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateIncidentResponse) Reset() {
+	*x = UpdateIncidentResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateIncidentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateIncidentResponse) ProtoMessage() {}
+
+func (x *UpdateIncidentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateIncidentResponse.ProtoReflect.Descriptor instead.
+func (*UpdateIncidentResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *UpdateIncidentResponse) GetIncident() *Incident {
+	if x != nil {
+		return x.Incident
+	}
+	return nil
+}
+
+type PatchIncidentRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title       *string                `protobuf:"bytes,2,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	Description *string                `protobuf:"bytes,3,opt,name=description,proto3,oneof" json:"description,omitempty"`
+	Latitude    *float64               `protobuf:"fixed64,4,opt,name=latitude,proto3,oneof" json:"latitude,omitempty"`
+	Longitude   *float64               `protobuf:"fixed64,5,opt,name=longitude,proto3,oneof" json:"longitude,omitempty"`
+	RadiusM     *int32                 `protobuf:"varint,6,opt,name=radius_m,json=radiusM,proto3,oneof" json:"radius_m,omitempty"`
+	Active      *bool                  `protobuf:"varint,7,opt,name=active,proto3,oneof" json:"active,omitempty"`
+	// 0 — без проверки версии
+	ExpectedVersion int64 `protobuf:"varint,8,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	// заменяет полигон
+	Polygon *Polygon `protobuf:"bytes,9,opt,name=polygon,proto3" json:"polygon,omitempty"`
+	// удаляет полигон (как "polygon": null в HTTP); вместе с ним нужен radius_m
+	ClearPolygon  bool `protobuf:"varint,10,opt,name=clear_polygon,json=clearPolygon,proto3" json:"clear_polygon,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchIncidentRequest) Reset() {
+	*x = PatchIncidentRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchIncidentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchIncidentRequest) ProtoMessage() {}
+
+func (x *PatchIncidentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchIncidentRequest.ProtoReflect.Descriptor instead.
+func (*PatchIncidentRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PatchIncidentRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PatchIncidentRequest) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+func (x *PatchIncidentRequest) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *PatchIncidentRequest) GetLatitude() float64 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *PatchIncidentRequest) GetLongitude() float64 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *PatchIncidentRequest) GetRadiusM() int32 {
+	if x != nil && x.RadiusM != nil {
+		return *x.RadiusM
+	}
+	return 0
+}
+
+func (x *PatchIncidentRequest) GetActive() bool {
+	if x != nil && x.Active != nil {
+		return *x.Active
+	}
+	return false
+}
+
+func (x *PatchIncidentRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+func (x *PatchIncidentRequest) GetPolygon() *Polygon {
+	if x != nil {
+		return x.Polygon
+	}
+	return nil
+}
+
+func (x *PatchIncidentRequest) GetClearPolygon() bool {
+	if x != nil {
+		return x.ClearPolygon
+	}
+	return false
+}
+
+type PatchIncidentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Incident      *Incident              `protobuf:"bytes,1,opt,name=incident,proto3" json:"incident,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PatchIncidentResponse) Reset() {
+	*x = PatchIncidentResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PatchIncidentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PatchIncidentResponse) ProtoMessage() {}
+
+func (x *PatchIncidentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PatchIncidentResponse.ProtoReflect.Descriptor instead.
+func (*PatchIncidentResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PatchIncidentResponse) GetIncident() *Incident {
+	if x != nil {
+		return x.Incident
+	}
+	return nil
+}
+
+type DeactivateIncidentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeactivateIncidentRequest) Reset() {
+	*x = DeactivateIncidentRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeactivateIncidentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeactivateIncidentRequest) ProtoMessage() {}
+
+func (x *DeactivateIncidentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeactivateIncidentRequest.ProtoReflect.Descriptor instead.
+func (*DeactivateIncidentRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeactivateIncidentRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeactivateIncidentResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeactivateIncidentResponse) Reset() {
+	*x = DeactivateIncidentResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeactivateIncidentResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeactivateIncidentResponse) ProtoMessage() {}
+
+func (x *DeactivateIncidentResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeactivateIncidentResponse.ProtoReflect.Descriptor instead.
+func (*DeactivateIncidentResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{15}
+}
+
+type CheckLocationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Latitude      float64                `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,3,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckLocationRequest) Reset() {
+	*x = CheckLocationRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckLocationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckLocationRequest) ProtoMessage() {}
+
+func (x *CheckLocationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckLocationRequest.ProtoReflect.Descriptor instead.
+func (*CheckLocationRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CheckLocationRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CheckLocationRequest) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *CheckLocationRequest) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+type CheckLocationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Latitude      float64                `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,3,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	LocationsIds  []int64                `protobuf:"varint,4,rep,packed,name=locations_ids,json=locationsIds,proto3" json:"locations_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckLocationResponse) Reset() {
+	*x = CheckLocationResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckLocationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckLocationResponse) ProtoMessage() {}
+
+func (x *CheckLocationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckLocationResponse.ProtoReflect.Descriptor instead.
+func (*CheckLocationResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *CheckLocationResponse) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *CheckLocationResponse) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *CheckLocationResponse) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *CheckLocationResponse) GetLocationsIds() []int64 {
+	if x != nil {
+		return x.LocationsIds
+	}
+	return nil
+}
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{18}
+}
+
+type GetStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserCount     int32                  `protobuf:"varint,1,opt,name=user_count,json=userCount,proto3" json:"user_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsResponse) Reset() {
+	*x = GetStatsResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsResponse) ProtoMessage() {}
+
+func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetStatsResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GetStatsResponse) GetUserCount() int32 {
+	if x != nil {
+		return x.UserCount
+	}
+	return 0
+}
+
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{20}
+}
+
+type HealthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Db            string                 `protobuf:"bytes,2,opt,name=db,proto3" json:"db,omitempty"`
+	Redis         string                 `protobuf:"bytes,3,opt,name=redis,proto3" json:"redis,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *HealthResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetRedis() string {
+	if x != nil {
+		return x.Redis
+	}
+	return ""
+}
+
+type WatchMatchesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 0 — совпадения всех пользователей
+	UserId int64 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// ID последнего полученного события для досылки пропущенных
+	LastEventId   string `protobuf:"bytes,2,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchMatchesRequest) Reset() {
+	*x = WatchMatchesRequest{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchMatchesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchMatchesRequest) ProtoMessage() {}
+
+func (x *WatchMatchesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchMatchesRequest.ProtoReflect.Descriptor instead.
+func (*WatchMatchesRequest) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WatchMatchesRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *WatchMatchesRequest) GetLastEventId() string {
+	if x != nil {
+		return x.LastEventId
+	}
+	return ""
+}
+
+type WatchMatchesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Latitude      float64                `protobuf:"fixed64,3,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64                `protobuf:"fixed64,4,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	LocationsIds  []int64                `protobuf:"varint,5,rep,packed,name=locations_ids,json=locationsIds,proto3" json:"locations_ids,omitempty"`
+	CheckedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=checked_at,json=checkedAt,proto3" json:"checked_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchMatchesResponse) Reset() {
+	*x = WatchMatchesResponse{}
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchMatchesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchMatchesResponse) ProtoMessage() {}
+
+func (x *WatchMatchesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geonotifications_v1_incidents_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchMatchesResponse.ProtoReflect.Descriptor instead.
+func (*WatchMatchesResponse) Descriptor() ([]byte, []int) {
+	return file_geonotifications_v1_incidents_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *WatchMatchesResponse) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *WatchMatchesResponse) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *WatchMatchesResponse) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *WatchMatchesResponse) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *WatchMatchesResponse) GetLocationsIds() []int64 {
+	if x != nil {
+		return x.LocationsIds
+	}
+	return nil
+}
+
+func (x *WatchMatchesResponse) GetCheckedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CheckedAt
+	}
+	return nil
+}
+
+var File_geonotifications_v1_incidents_proto protoreflect.FileDescriptor
+
+const file_geonotifications_v1_incidents_proto_rawDesc = "" +
+	"\n" +
+	"#geonotifications/v1/incidents.proto\x12\x13geonotifications.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x87\x03\n" +
+	"\bIncident\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1a\n" +
+	"\blatitude\x18\x04 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x05 \x01(\x01R\tlongitude\x12\x19\n" +
+	"\bradius_m\x18\x06 \x01(\x05R\aradiusM\x12\x16\n" +
+	"\x06active\x18\a \x01(\bR\x06active\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x18\n" +
+	"\aversion\x18\n" +
+	" \x01(\x03R\aversion\x126\n" +
+	"\apolygon\x18\v \x01(\v2\x1c.geonotifications.v1.PolygonR\apolygon\":\n" +
+	"\aPolygon\x12/\n" +
+	"\x05rings\x18\x01 \x03(\v2\x19.geonotifications.v1.RingR\x05rings\":\n" +
+	"\x04Ring\x122\n" +
+	"\x06points\x18\x01 \x03(\v2\x1a.geonotifications.v1.PointR\x06points\"A\n" +
+	"\x05Point\x12\x1c\n" +
+	"\tlongitude\x18\x01 \x01(\x01R\tlongitude\x12\x1a\n" +
+	"\blatitude\x18\x02 \x01(\x01R\blatitude\"\xdc\x01\n" +
+	"\x15CreateIncidentRequest\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1a\n" +
+	"\blatitude\x18\x03 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x04 \x01(\x01R\tlongitude\x12\x19\n" +
+	"\bradius_m\x18\x05 \x01(\x05R\aradiusM\x126\n" +
+	"\apolygon\x18\x06 \x01(\v2\x1c.geonotifications.v1.PolygonR\apolygon\"S\n" +
+	"\x16CreateIncidentResponse\x129\n" +
+	"\bincident\x18\x01 \x01(\v2\x1d.geonotifications.v1.IncidentR\bincident\"$\n" +
+	"\x12GetIncidentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"P\n" +
+	"\x13GetIncidentResponse\x129\n" +
+	"\bincident\x18\x01 \x01(\v2\x1d.geonotifications.v1.IncidentR\bincident\"G\n" +
+	"\x14ListIncidentsRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\"}\n" +
+	"\x15ListIncidentsResponse\x123\n" +
+	"\x05items\x18\x01 \x03(\v2\x1d.geonotifications.v1.IncidentR\x05items\x12\x12\n" +
+	"\x04page\x18\x02 \x01(\x05R\x04page\x12\x1b\n" +
+	"\tpage_size\x18\x03 \x01(\x05R\bpageSize\"}\n" +
+	"\x15UpdateIncidentRequest\x129\n" +
+	"\bincident\x18\x01 \x01(\v2\x1d.geonotifications.v1.IncidentR\bincident\x12)\n" +
+	"\x10expected_version\x18\x02 \x01(\x03R\x0fexpectedVersion\"S\n" +
+	"\x16UpdateIncidentResponse\x129\n" +
+	"\bincident\x18\x01 \x01(\v2\x1d.geonotifications.v1.IncidentR\bincident\"\xbe\x03\n" +
+	"\x14PatchIncidentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\x05title\x18\x02 \x01(\tH\x00R\x05title\x88\x01\x01\x12%\n" +
+	"\vdescription\x18\x03 \x01(\tH\x01R\vdescription\x88\x01\x01\x12\x1f\n" +
+	"\blatitude\x18\x04 \x01(\x01H\x02R\blatitude\x88\x01\x01\x12!\n" +
+	"\tlongitude\x18\x05 \x01(\x01H\x03R\tlongitude\x88\x01\x01\x12\x1e\n" +
+	"\bradius_m\x18\x06 \x01(\x05H\x04R\aradiusM\x88\x01\x01\x12\x1b\n" +
+	"\x06active\x18\a \x01(\bH\x05R\x06active\x88\x01\x01\x12)\n" +
+	"\x10expected_version\x18\b \x01(\x03R\x0fexpectedVersion\x126\n" +
+	"\apolygon\x18\t \x01(\v2\x1c.geonotifications.v1.PolygonR\apolygon\x12#\n" +
+	"\rclear_polygon\x18\n" +
+	" \x01(\bR\fclearPolygonB\b\n" +
+	"\x06_titleB\x0e\n" +
+	"\f_descriptionB\v\n" +
+	"\t_latitudeB\f\n" +
+	"\n" +
+	"_longitudeB\v\n" +
+	"\t_radius_mB\t\n" +
+	"\a_active\"R\n" +
+	"\x15PatchIncidentResponse\x129\n" +
+	"\bincident\x18\x01 \x01(\v2\x1d.geonotifications.v1.IncidentR\bincident\"+\n" +
+	"\x19DeactivateIncidentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x1c\n" +
+	"\x1aDeactivateIncidentResponse\"i\n" +
+	"\x14CheckLocationRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1a\n" +
+	"\blatitude\x18\x02 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x03 \x01(\x01R\tlongitude\"\x8f\x01\n" +
+	"\x15CheckLocationResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1a\n" +
+	"\blatitude\x18\x02 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x03 \x01(\x01R\tlongitude\x12#\n" +
+	"\rlocations_ids\x18\x04 \x03(\x03R\flocationsIds\"\x11\n" +
+	"\x0fGetStatsRequest\"1\n" +
+	"\x10GetStatsResponse\x12\x1d\n" +
+	"\n" +
+	"user_count\x18\x01 \x01(\x05R\tuserCount\"\x0f\n" +
+	"\rHealthRequest\"N\n" +
+	"\x0eHealthResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x0e\n" +
+	"\x02db\x18\x02 \x01(\tR\x02db\x12\x14\n" +
+	"\x05redis\x18\x03 \x01(\tR\x05redis\"R\n" +
+	"\x13WatchMatchesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\"\n" +
+	"\rlast_event_id\x18\x02 \x01(\tR\vlastEventId\"\xe4\x01\n" +
+	"\x14WatchMatchesResponse\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12\x1a\n" +
+	"\blatitude\x18\x03 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x04 \x01(\x01R\tlongitude\x12#\n" +
+	"\rlocations_ids\x18\x05 \x03(\x03R\flocationsIds\x129\n" +
+	"\n" +
+	"checked_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcheckedAt2\x8b\b\n" +
+	"\x0fIncidentService\x12i\n" +
+	"\x0eCreateIncident\x12*.geonotifications.v1.CreateIncidentRequest\x1a+.geonotifications.v1.CreateIncidentResponse\x12`\n" +
+	"\vGetIncident\x12'.geonotifications.v1.GetIncidentRequest\x1a(.geonotifications.v1.GetIncidentResponse\x12f\n" +
+	"\rListIncidents\x12).geonotifications.v1.ListIncidentsRequest\x1a*.geonotifications.v1.ListIncidentsResponse\x12i\n" +
+	"\x0eUpdateIncident\x12*.geonotifications.v1.UpdateIncidentRequest\x1a+.geonotifications.v1.UpdateIncidentResponse\x12f\n" +
+	"\rPatchIncident\x12).geonotifications.v1.PatchIncidentRequest\x1a*.geonotifications.v1.PatchIncidentResponse\x12u\n" +
+	"\x12DeactivateIncident\x12..geonotifications.v1.DeactivateIncidentRequest\x1a/.geonotifications.v1.DeactivateIncidentResponse\x12f\n" +
+	"\rCheckLocation\x12).geonotifications.v1.CheckLocationRequest\x1a*.geonotifications.v1.CheckLocationResponse\x12W\n" +
+	"\bGetStats\x12$.geonotifications.v1.GetStatsRequest\x1a%.geonotifications.v1.GetStatsResponse\x12Q\n" +
+	"\x06Health\x12\".geonotifications.v1.HealthRequest\x1a#.geonotifications.v1.HealthResponse\x12e\n" +
+	"\fWatchMatches\x12(.geonotifications.v1.WatchMatchesRequest\x1a).geonotifications.v1.WatchMatchesResponse0\x01BFZDgeo-notifications/internal/pb/geonotifications/v1;geonotificationsv1b\x06proto3"
+
+var (
+	file_geonotifications_v1_incidents_proto_rawDescOnce sync.Once
+	file_geonotifications_v1_incidents_proto_rawDescData []byte
+)
+
+func file_geonotifications_v1_incidents_proto_rawDescGZIP() []byte {
+	file_geonotifications_v1_incidents_proto_rawDescOnce.Do(func() {
+		file_geonotifications_v1_incidents_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_geonotifications_v1_incidents_proto_rawDesc), len(file_geonotifications_v1_incidents_proto_rawDesc)))
+	})
+	return file_geonotifications_v1_incidents_proto_rawDescData
+}
+
+var file_geonotifications_v1_incidents_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
+var file_geonotifications_v1_incidents_proto_goTypes = []any{
+	(*Incident)(nil),                   // 0: geonotifications.v1.Incident
+	(*Polygon)(nil),                    // 1: geonotifications.v1.Polygon
+	(*Ring)(nil),                       // 2: geonotifications.v1.Ring
+	(*Point)(nil),                      // 3: geonotifications.v1.Point
+	(*CreateIncidentRequest)(nil),      // 4: geonotifications.v1.CreateIncidentRequest
+	(*CreateIncidentResponse)(nil),     // 5: geonotifications.v1.CreateIncidentResponse
+	(*GetIncidentRequest)(nil),         // 6: geonotifications.v1.GetIncidentRequest
+	(*GetIncidentResponse)(nil),        // 7: geonotifications.v1.GetIncidentResponse
+	(*ListIncidentsRequest)(nil),       // 8: geonotifications.v1.ListIncidentsRequest
+	(*ListIncidentsResponse)(nil),      // 9: geonotifications.v1.ListIncidentsResponse
+	(*UpdateIncidentRequest)(nil),      // 10: geonotifications.v1.UpdateIncidentRequest
+	(*UpdateIncidentResponse)(nil),     // 11: geonotifications.v1.UpdateIncidentResponse
+	(*PatchIncidentRequest)(nil),       // 12: geonotifications.v1.PatchIncidentRequest
+	(*PatchIncidentResponse)(nil),      // 13: geonotifications.v1.PatchIncidentResponse
+	(*DeactivateIncidentRequest)(nil),  // 14: geonotifications.v1.DeactivateIncidentRequest
+	(*DeactivateIncidentResponse)(nil), // 15: geonotifications.v1.DeactivateIncidentResponse
+	(*CheckLocationRequest)(nil),       // 16: geonotifications.v1.CheckLocationRequest
+	(*CheckLocationResponse)(nil),      // 17: geonotifications.v1.CheckLocationResponse
+	(*GetStatsRequest)(nil),            // 18: geonotifications.v1.GetStatsRequest
+	(*GetStatsResponse)(nil),           // 19: geonotifications.v1.GetStatsResponse
+	(*HealthRequest)(nil),              // 20: geonotifications.v1.HealthRequest
+	(*HealthResponse)(nil),             // 21: geonotifications.v1.HealthResponse
+	(*WatchMatchesRequest)(nil),        // 22: geonotifications.v1.WatchMatchesRequest
+	(*WatchMatchesResponse)(nil),       // 23: geonotifications.v1.WatchMatchesResponse
+	(*timestamppb.Timestamp)(nil),      // 24: google.protobuf.Timestamp
+}
+var file_geonotifications_v1_incidents_proto_depIdxs = []int32{
+	24, // 0: geonotifications.v1.Incident.created_at:type_name -> google.protobuf.Timestamp
+	24, // 1: geonotifications.v1.Incident.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: geonotifications.v1.Incident.polygon:type_name -> geonotifications.v1.Polygon
+	2,  // 3: geonotifications.v1.Polygon.rings:type_name -> geonotifications.v1.Ring
+	3,  // 4: geonotifications.v1.Ring.points:type_name -> geonotifications.v1.Point
+	1,  // 5: geonotifications.v1.CreateIncidentRequest.polygon:type_name -> geonotifications.v1.Polygon
+	0,  // 6: geonotifications.v1.CreateIncidentResponse.incident:type_name -> geonotifications.v1.Incident
+	0,  // 7: geonotifications.v1.GetIncidentResponse.incident:type_name -> geonotifications.v1.Incident
+	0,  // 8: geonotifications.v1.ListIncidentsResponse.items:type_name -> geonotifications.v1.Incident
+	0,  // 9: geonotifications.v1.UpdateIncidentRequest.incident:type_name -> geonotifications.v1.Incident
+	0,  // 10: geonotifications.v1.UpdateIncidentResponse.incident:type_name -> geonotifications.v1.Incident
+	1,  // 11: geonotifications.v1.PatchIncidentRequest.polygon:type_name -> geonotifications.v1.Polygon
+	0,  // 12: geonotifications.v1.PatchIncidentResponse.incident:type_name -> geonotifications.v1.Incident
+	24, // 13: geonotifications.v1.WatchMatchesResponse.checked_at:type_name -> google.protobuf.Timestamp
+	4,  // 14: geonotifications.v1.IncidentService.CreateIncident:input_type -> geonotifications.v1.CreateIncidentRequest
+	6,  // 15: geonotifications.v1.IncidentService.GetIncident:input_type -> geonotifications.v1.GetIncidentRequest
+	8,  // 16: geonotifications.v1.IncidentService.ListIncidents:input_type -> geonotifications.v1.ListIncidentsRequest
+	10, // 17: geonotifications.v1.IncidentService.UpdateIncident:input_type -> geonotifications.v1.UpdateIncidentRequest
+	12, // 18: geonotifications.v1.IncidentService.PatchIncident:input_type -> geonotifications.v1.PatchIncidentRequest
+	14, // 19: geonotifications.v1.IncidentService.DeactivateIncident:input_type -> geonotifications.v1.DeactivateIncidentRequest
+	16, // 20: geonotifications.v1.IncidentService.CheckLocation:input_type -> geonotifications.v1.CheckLocationRequest
+	18, // 21: geonotifications.v1.IncidentService.GetStats:input_type -> geonotifications.v1.GetStatsRequest
+	20, // 22: geonotifications.v1.IncidentService.Health:input_type -> geonotifications.v1.HealthRequest
+	22, // 23: geonotifications.v1.IncidentService.WatchMatches:input_type -> geonotifications.v1.WatchMatchesRequest
+	5,  // 24: geonotifications.v1.IncidentService.CreateIncident:output_type -> geonotifications.v1.CreateIncidentResponse
+	7,  // 25: geonotifications.v1.IncidentService.GetIncident:output_type -> geonotifications.v1.GetIncidentResponse
+	9,  // 26: geonotifications.v1.IncidentService.ListIncidents:output_type -> geonotifications.v1.ListIncidentsResponse
+	11, // 27: geonotifications.v1.IncidentService.UpdateIncident:output_type -> geonotifications.v1.UpdateIncidentResponse
+	13, // 28: geonotifications.v1.IncidentService.PatchIncident:output_type -> geonotifications.v1.PatchIncidentResponse
+	15, // 29: geonotifications.v1.IncidentService.DeactivateIncident:output_type -> geonotifications.v1.DeactivateIncidentResponse
+	17, // 30: geonotifications.v1.IncidentService.CheckLocation:output_type -> geonotifications.v1.CheckLocationResponse
+	19, // 31: geonotifications.v1.IncidentService.GetStats:output_type -> geonotifications.v1.GetStatsResponse
+	21, // 32: geonotifications.v1.IncidentService.Health:output_type -> geonotifications.v1.HealthResponse
+	23, // 33: geonotifications.v1.IncidentService.WatchMatches:output_type -> geonotifications.v1.WatchMatchesResponse
+	24, // [24:34] is the sub-list for method output_type
+	14, // [14:24] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_geonotifications_v1_incidents_proto_init() }
+func file_geonotifications_v1_incidents_proto_init() {
+	if File_geonotifications_v1_incidents_proto != nil {
+		return
+	}
+	file_geonotifications_v1_incidents_proto_msgTypes[12].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_geonotifications_v1_incidents_proto_rawDesc), len(file_geonotifications_v1_incidents_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   24,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_geonotifications_v1_incidents_proto_goTypes,
+		DependencyIndexes: file_geonotifications_v1_incidents_proto_depIdxs,
+		MessageInfos:      file_geonotifications_v1_incidents_proto_msgTypes,
+	}.Build()
+	File_geonotifications_v1_incidents_proto = out.File
+	file_geonotifications_v1_incidents_proto_goTypes = nil
+	file_geonotifications_v1_incidents_proto_depIdxs = nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"geo-notifications/internal/model"
+)
+
+func (s *Storage) CreateAPIKey(ctx context.Context, in *model.APIKey, keyHash string) error {
+	query := `
+INSERT INTO api_keys (tenant, name, role, prefix, key_hash)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, created_at;
+`
+	return s.repo.db.QueryRowContext(ctx, query, in.Tenant, in.Name, in.Role, in.Prefix, keyHash).
+		Scan(&in.ID, &in.CreatedAt)
+}
+
+// ListAPIKeys возвращает ключи тенанта; пустой tenant — ключи всех тенантов.
+func (s *Storage) ListAPIKeys(ctx context.Context, tenant string) ([]model.APIKey, error) {
+	query := `
+SELECT id, tenant, name, role, prefix, created_at, revoked_at
+FROM api_keys
+WHERE $1::text = '' OR tenant = $1
+ORDER BY id;
+`
+	rows, err := s.repo.db.QueryContext(ctx, query, tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.APIKey
+	for rows.Next() {
+		var k model.APIKey
+		if err := rows.Scan(&k.ID, &k.Tenant, &k.Name, &k.Role, &k.Prefix, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetActiveAPIKeyByHash ищет неотозванный ключ по хэшу; nil, если не найден.
+func (s *Storage) GetActiveAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	query := `
+SELECT id, tenant, name, role, prefix, created_at, revoked_at
+FROM api_keys
+WHERE key_hash = $1 AND revoked_at IS NULL;
+`
+	var k model.APIKey
+	err := s.repo.db.QueryRowContext(ctx, query, keyHash).
+		Scan(&k.ID, &k.Tenant, &k.Name, &k.Role, &k.Prefix, &k.CreatedAt, &k.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// RevokeAPIKey отзывает ключ тенанта (пустой tenant — любого); false, если
+// активного ключа с таким id нет.
+func (s *Storage) RevokeAPIKey(ctx context.Context, tenant string, id int64) (bool, error) {
+	query := `
+UPDATE api_keys
+SET revoked_at = NOW()
+WHERE id = $1 AND ($2::text = '' OR tenant = $2) AND revoked_at IS NULL;
+`
+	res, err := s.repo.db.ExecContext(ctx, query, id, tenant)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
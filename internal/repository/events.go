@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"geo-notifications/internal/model"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	eventsChannel = "events"
+	eventsLogKey  = "events_log"
+	// сколько событий держим в стриме для восстановления по Last-Event-ID
+	eventsRetention = 10 * time.Minute
+)
+
+// PublishEvent сохраняет событие в короткий буфер (Redis Stream) и рассылает
+// его всем репликам через pub/sub. ID события — ID записи в стриме.
+func (s *Storage) PublishEvent(ctx context.Context, ev *model.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	minID := strconv.FormatInt(time.Now().Add(-eventsRetention).UnixMilli(), 10) + "-0"
+	id, err := s.cache.cache.XAdd(ctx, &redis.XAddArgs{
+		Stream: eventsLogKey,
+		MinID:  minID,
+		Approx: true,
+		Values: map[string]interface{}{"data": data},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("xadd event: %w", err)
+	}
+	ev.ID = id
+
+	data, err = json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	if err := s.cache.cache.Publish(ctx, eventsChannel, data).Err(); err != nil {
+		return fmt.Errorf("publish event: %w", err)
+	}
+	return nil
+}
+
+// EventsSince возвращает события из буфера, идущие строго после lastID.
+func (s *Storage) EventsSince(ctx context.Context, lastID string) ([]model.Event, error) {
+	msgs, err := s.cache.cache.XRange(ctx, eventsLogKey, "("+lastID, "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("xrange events: %w", err)
+	}
+
+	result := make([]model.Event, 0, len(msgs))
+	for _, msg := range msgs {
+		raw, ok := msg.Values["data"].(string)
+		if !ok {
+			continue
+		}
+		var ev model.Event
+		if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+			return nil, fmt.Errorf("unmarshal event %s: %w", msg.ID, err)
+		}
+		ev.ID = msg.ID
+		result = append(result, ev)
+	}
+	return result, nil
+}
+
+// SubscribeEvents подписывается на канал событий. Канал закрывается после
+// отмены ctx.
+func (s *Storage) SubscribeEvents(ctx context.Context) (<-chan model.Event, error) {
+	pubsub := s.cache.cache.Subscribe(ctx, eventsChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("subscribe events: %w", err)
+	}
+
+	out := make(chan model.Event)
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var ev model.Event
+				if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+					continue
+				}
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
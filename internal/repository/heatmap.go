@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"geo-notifications/internal/model"
+)
+
+// HeatmapFilter — какие проверки и в какую сетку собирать. Сетка делит
+// широту на Rows, а долготу на Cols равных частей.
+type HeatmapFilter struct {
+	From time.Time
+	To   time.Time
+	Rows int64
+	Cols int64
+	// minLon, minLat, maxLon, maxLat
+	BBox         [4]float64
+	OnlyMatching bool
+	MinUsers     int
+	// сколько ячеек вернуть самое большее
+	Limit int
+}
+
+// ячейки считаются в базе, наружу уходят только агрегаты; точки на самой
+// границе (широта 90, долгота 180) попадают в крайнюю ячейку
+const heatmapCellsQuery = `
+WITH cells AS (
+    SELECT LEAST(floor((latitude + 90) * $4 / 180), $4 - 1)::bigint AS row,
+           LEAST(floor((longitude + 180) * $5 / 360), $5 - 1)::bigint AS col,
+           COUNT(*) AS checks,
+           COUNT(DISTINCT user_id) AS users
+    FROM locations_check
+    WHERE tenant = $1 AND checked_at >= $2 AND checked_at < $3
+      AND ($6 = FALSE OR cardinality(incident_ids) > 0)
+      AND longitude BETWEEN $7 AND $9 AND latitude BETWEEN $8 AND $10
+    GROUP BY 1, 2
+)
+`
+
+// GetHeatmapCells возвращает ячейки, где было не меньше MinUsers разных
+// пользователей, и число скрытых ячеек. Ячеек может быть Limit+1 — так
+// видно, что лимит превышен.
+func (s *Storage) GetHeatmapCells(ctx context.Context, tenant string, f HeatmapFilter) ([]model.HeatmapCell, int, error) {
+	args := []any{tenant, f.From, f.To, f.Rows, f.Cols, f.OnlyMatching,
+		f.BBox[0], f.BBox[1], f.BBox[2], f.BBox[3], f.MinUsers}
+
+	tx, err := s.repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	var suppressed int
+	querySuppressed := heatmapCellsQuery + `SELECT COUNT(*) FROM cells WHERE users < $11;`
+	if err := tx.QueryRowContext(ctx, querySuppressed, args...).Scan(&suppressed); err != nil {
+		return nil, 0, err
+	}
+
+	queryCells := heatmapCellsQuery + `
+SELECT row, col, checks, users
+FROM cells
+WHERE users >= $11
+ORDER BY row, col
+LIMIT $12;
+`
+	rows, err := tx.QueryContext(ctx, queryCells, append(args, f.Limit+1)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	cells := []model.HeatmapCell{}
+	for rows.Next() {
+		var c model.HeatmapCell
+		if err := rows.Scan(&c.Row, &c.Col, &c.Checks, &c.Users); err != nil {
+			return nil, 0, err
+		}
+		cells = append(cells, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return cells, suppressed, nil
+}
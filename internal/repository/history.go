@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"geo-notifications/internal/model"
+)
+
+// changeIncident блокирует строку инцидента, проверяет версию и вызывает
+// change; если тот вернул новый инцидент, в той же транзакции пишется
+// запись истории. change возвращает nil, когда менять нечего — тогда
+// возвращается текущий инцидент и false.
+func (s *Storage) changeIncident(ctx context.Context, tenant, actor string, id, ifVersion int64, action string,
+	change func(tx *sql.Tx, before *model.Incident) (*model.Incident, error)) (*model.Incident, bool, error) {
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE id = $1 AND tenant = $2 FOR UPDATE;`
+	before, err := scanIncident(tx.QueryRowContext(ctx, query, id, tenant))
+	if err == sql.ErrNoRows {
+		return nil, false, ErrNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if ifVersion != 0 && before.Version != ifVersion {
+		return nil, false, ErrVersionMismatch
+	}
+
+	after, err := change(tx, before)
+	if err != nil {
+		return nil, false, err
+	}
+	if after == nil {
+		return before, false, nil
+	}
+
+	if err := insertHistory(ctx, tx, tenant, actor, action, before, after); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return after, true, nil
+}
+
+// insertHistory пишет запись истории; before == nil — создание,
+// after == nil — удаление.
+func insertHistory(ctx context.Context, tx *sql.Tx, tenant, actor, action string, before, after *model.Incident) error {
+	changes, err := json.Marshal(diffIncidents(before, after))
+	if err != nil {
+		return err
+	}
+	target := after
+	if target == nil {
+		target = before
+	}
+
+	query := `
+INSERT INTO incident_history (tenant, incident_id, action, actor, version, changes)
+VALUES ($1, $2, $3, $4, $5, $6);
+`
+	if _, err := tx.ExecContext(ctx, query, tenant, target.ID, action, actor, target.Version, changes); err != nil {
+		return fmt.Errorf("insert incident history: %w", err)
+	}
+	return nil
+}
+
+// diffIncidents возвращает изменившиеся поля. При создании (before == nil)
+// и удалении (after == nil) в diff попадают все поля.
+func diffIncidents(before, after *model.Incident) map[string]model.FieldChange {
+	diff := make(map[string]model.FieldChange)
+	add := func(field string, from, to any, changed bool) {
+		switch {
+		case before == nil:
+			diff[field] = model.FieldChange{To: to}
+		case after == nil:
+			diff[field] = model.FieldChange{From: from}
+		case changed:
+			diff[field] = model.FieldChange{From: from, To: to}
+		}
+	}
+	b, a := before, after
+	if b == nil {
+		b = &model.Incident{}
+	}
+	if a == nil {
+		a = &model.Incident{}
+	}
+	add("title", b.Title, a.Title, b.Title != a.Title)
+	add("description", b.Description, a.Description, b.Description != a.Description)
+	add("latitude", b.Latitude, a.Latitude, b.Latitude != a.Latitude)
+	add("longitude", b.Longitude, a.Longitude, b.Longitude != a.Longitude)
+	add("radius_m", b.RadiusM, a.RadiusM, b.RadiusM != a.RadiusM)
+	add("active", b.Active, a.Active, b.Active != a.Active)
+	add("polygon", b.Polygon, a.Polygon, !reflect.DeepEqual(b.Polygon, a.Polygon))
+	return diff
+}
+
+func sameFields(a, b *model.Incident) bool {
+	return len(diffIncidents(a, b)) == 0
+}
+
+// GetIncidentHistory возвращает историю инцидента от старых записей к новым.
+func (s *Storage) GetIncidentHistory(ctx context.Context, tenant string, incidentID int64) ([]model.IncidentChange, error) {
+	query := `
+SELECT id, incident_id, action, actor, version, changes, created_at
+FROM incident_history
+WHERE tenant = $1 AND incident_id = $2
+ORDER BY id;
+`
+	rows, err := s.repo.db.QueryContext(ctx, query, tenant, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.IncidentChange
+	for rows.Next() {
+		var c model.IncidentChange
+		var changes []byte
+		if err := rows.Scan(&c.ID, &c.IncidentID, &c.Action, &c.Actor, &c.Version, &changes, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &c.Changes); err != nil {
+			return nil, fmt.Errorf("decode history changes: %w", err)
+		}
+		result = append(result, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
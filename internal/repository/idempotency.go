@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"geo-notifications/internal/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyPrefix = "idempotency:"
+
+// ReserveIdempotencyKey атомарно занимает ключ записью rec. Если ключ уже
+// занят, возвращает false и сохранённую запись.
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key string, rec model.IdempotencyRecord, ttl time.Duration) (bool, *model.IdempotencyRecord, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return false, nil, fmt.Errorf("marshal idempotency record: %w", err)
+	}
+
+	// запись может истечь между SETNX и GET — тогда пробуем занять ещё раз
+	for attempt := 0; attempt < 2; attempt++ {
+		ok, err := s.cache.cache.SetNX(ctx, idempotencyPrefix+key, data, ttl).Result()
+		if err != nil {
+			return false, nil, fmt.Errorf("reserve idempotency key: %w", err)
+		}
+		if ok {
+			return true, nil, nil
+		}
+
+		raw, err := s.cache.cache.Get(ctx, idempotencyPrefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return false, nil, fmt.Errorf("get idempotency record: %w", err)
+		}
+
+		var stored model.IdempotencyRecord
+		if err := json.Unmarshal(raw, &stored); err != nil {
+			return false, nil, fmt.Errorf("unmarshal idempotency record: %w", err)
+		}
+		return false, &stored, nil
+	}
+	return false, nil, fmt.Errorf("reserve idempotency key: record keeps expiring")
+}
+
+func (s *Storage) SaveIdempotencyRecord(ctx context.Context, key string, rec model.IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal idempotency record: %w", err)
+	}
+	if err := s.cache.cache.Set(ctx, idempotencyPrefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("save idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	if err := s.cache.cache.Del(ctx, idempotencyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("delete idempotency key: %w", err)
+	}
+	return nil
+}
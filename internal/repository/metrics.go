@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// сколько коллектор ждёт базу и Redis при одном опросе
+const collectTimeout = 3 * time.Second
+
+var (
+	upDesc = prometheus.NewDesc("geo_dependency_up",
+		"Whether the dependency answered a ping (1) or not (0).", []string{"dependency"}, nil)
+	pingDesc = prometheus.NewDesc("geo_dependency_ping_seconds",
+		"Ping latency of the dependency.", []string{"dependency"}, nil)
+	activeIncidentsDesc = prometheus.NewDesc("geo_incidents_active",
+		"Active incidents by tenant.", []string{"tenant"}, nil)
+	queueDepthDesc = prometheus.NewDesc("geo_webhook_queue_depth",
+		"Webhook tasks waiting in the queue by tenant.", []string{"tenant"}, nil)
+)
+
+// storageCollector снимает состояние базы и Redis в момент опроса /metrics,
+// поэтому метрики не устаревают и не требуют фонового обновления.
+type storageCollector struct {
+	storage *Storage
+}
+
+// Collector возвращает коллектор Prometheus для доступности базы и Redis,
+// числа активных инцидентов и глубины очередей вебхуков.
+func (s *Storage) Collector() prometheus.Collector {
+	return &storageCollector{storage: s}
+}
+
+func (c *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- pingDesc
+	ch <- activeIncidentsDesc
+	ch <- queueDepthDesc
+}
+
+func (c *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	dbUp := c.collectPing(ctx, ch, "postgres", c.storage.PingDB)
+	redisUp := c.collectPing(ctx, ch, "redis", c.storage.PingRedis)
+
+	// без зависимости её метрики не отдаём, а не отдаём нули
+	if dbUp {
+		c.collectActiveIncidents(ctx, ch)
+	}
+	if redisUp {
+		c.collectQueueDepth(ctx, ch)
+	}
+}
+
+func (c *storageCollector) collectPing(ctx context.Context, ch chan<- prometheus.Metric, name string, ping func(context.Context) error) bool {
+	start := time.Now()
+	err := ping(ctx)
+	up := 0.0
+	if err == nil {
+		up = 1
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, up, name)
+	ch <- prometheus.MustNewConstMetric(pingDesc, prometheus.GaugeValue, time.Since(start).Seconds(), name)
+	return err == nil
+}
+
+func (c *storageCollector) collectActiveIncidents(ctx context.Context, ch chan<- prometheus.Metric) {
+	query := `SELECT tenant, COUNT(*) FROM incidents WHERE active GROUP BY tenant;`
+	rows, err := c.storage.repo.db.QueryContext(ctx, query)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(activeIncidentsDesc, err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tenant string
+		var count float64
+		if err := rows.Scan(&tenant, &count); err != nil {
+			ch <- prometheus.NewInvalidMetric(activeIncidentsDesc, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(activeIncidentsDesc, prometheus.GaugeValue, count, tenant)
+	}
+	if err := rows.Err(); err != nil {
+		ch <- prometheus.NewInvalidMetric(activeIncidentsDesc, err)
+	}
+}
+
+func (c *storageCollector) collectQueueDepth(ctx context.Context, ch chan<- prometheus.Metric) {
+	depths, err := c.storage.WebhookQueueDepths(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(queueDepthDesc, err)
+		return
+	}
+	for tenant, depth := range depths {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth), tenant)
+	}
+}
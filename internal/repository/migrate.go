@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Миграции лежат в migrations/ парами <версия>_<имя>.up.sql и .down.sql и
+// вшиваются в бинарник. Каждая выполняется в своей транзакции вместе с
+// записью в schema_migrations.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationsLockKey — ключ pg_advisory_lock: реплики, стартующие
+// одновременно, применяют миграции по очереди, а не наперегонки.
+const migrationsLockKey int64 = 0x67656f5f6d6967 // "geo_mig"
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration — версия схемы из migrations/.
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// checksum ловит правку уже применённой миграции: такая правка не дойдёт
+// до баз, где миграция уже прошла.
+func (m Migration) checksum() string {
+	sum := sha256.Sum256([]byte(m.up))
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrationStatus — миграция и время её применения; AppliedAt пуст у
+// ожидающих. Unknown — версия есть в базе, но не в этом бинарнике
+// (базу мигрировала более новая версия сервиса).
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func embeddedMigrations() ([]Migration, error) {
+	dir, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return loadMigrations(dir)
+}
+
+// loadMigrations читает миграции из корня fsys и сортирует их по версии.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		parts := migrationFileRe.FindStringSubmatch(e.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", e.Name())
+		}
+		version, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %s needs both up and down files", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate применяет к базе все новые миграции.
+func (s *Storage) Migrate(ctx context.Context) ([]Migration, error) {
+	return s.repo.MigrateUp(ctx)
+}
+
+// MigrateUp применяет миграции, которых ещё нет в schema_migrations, по
+// возрастанию версии и возвращает применённые.
+func (r *PostgresRepo) MigrateUp(ctx context.Context) ([]Migration, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = r.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if a, ok := applied[m.Version]; ok {
+				if a.checksum != m.checksum() {
+					return fmt.Errorf("migration %s was changed after it had been applied", m)
+				}
+				continue
+			}
+			err := runMigration(ctx, conn, m.up,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3);`,
+				m.Version, m.Name, m.checksum())
+			if err != nil {
+				return fmt.Errorf("apply migration %s: %w", m, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown откатывает steps последних применённых миграций и
+// возвращает откаченные.
+func (r *PostgresRepo) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	var done []Migration
+	err = r.withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions {
+			if len(done) == steps {
+				break
+			}
+			m, ok := known[v]
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is not known to this binary", v, applied[v].name)
+			}
+			err := runMigration(ctx, conn, m.down, `DELETE FROM schema_migrations WHERE version = $1;`, m.Version)
+			if err != nil {
+				return fmt.Errorf("revert migration %s: %w", m, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrationStatus перечисляет миграции бинарника и применённые в базе.
+func (r *PostgresRepo) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			st.AppliedAt = &a.appliedAt
+			delete(applied, m.Version)
+		}
+		statuses = append(statuses, st)
+	}
+	for v, a := range applied {
+		statuses = append(statuses, MigrationStatus{Version: v, Name: a.name, AppliedAt: &a.appliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func (r *PostgresRepo) Close() error {
+	return r.db.Close()
+}
+
+// withMigrationLock выполняет fn на отдельном соединении под
+// pg_advisory_lock. Блокировка сессионная, поэтому берётся и снимается на
+// том же соединении; если процесс упадёт, её снимет закрытие соединения.
+func (r *PostgresRepo) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationsLockKey); err != nil {
+		return fmt.Errorf("acquire migrations lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationsLockKey)
+
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *sql.Conn) error {
+	query := `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    BIGINT      PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    checksum   TEXT        NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+`
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("create table schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var v int64
+		var a appliedMigration
+		if err := rows.Scan(&v, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[v] = a
+	}
+	return applied, rows.Err()
+}
+
+// runMigration выполняет SQL миграции и запись в schema_migrations одной
+// транзакцией: упавшая миграция не оставляет схему наполовину изменённой.
+func runMigration(ctx context.Context, conn *sql.Conn, body, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"geo-notifications/internal/config"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+
+	t.Run("sorted by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"0010_add_index.up.sql":   file("CREATE INDEX"),
+			"0010_add_index.down.sql": file("DROP INDEX"),
+			"0002_users.up.sql":       file("CREATE TABLE users"),
+			"0002_users.down.sql":     file("DROP TABLE users"),
+			"0001_initial.up.sql":     file("CREATE TABLE incidents"),
+			"0001_initial.down.sql":   file("DROP TABLE incidents"),
+		}
+		migrations, err := loadMigrations(fsys)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []string{"0001_initial", "0002_users", "0010_add_index"}
+		if len(migrations) != len(want) {
+			t.Fatalf("expected %d migrations, got %v", len(want), migrations)
+		}
+		for i, m := range migrations {
+			if m.String() != want[i] {
+				t.Fatalf("migration %d: expected %s, got %s", i, want[i], m)
+			}
+		}
+		if m := migrations[1]; m.Version != 2 || m.Name != "users" || m.up != "CREATE TABLE users" || m.down != "DROP TABLE users" {
+			t.Fatalf("unexpected migration: %+v", m)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{
+				"0001_initial.up.sql":   file("CREATE TABLE incidents"),
+				"0001_initial.down.sql": file("DROP TABLE incidents"),
+				"0002_users.up.sql":     file("CREATE TABLE users"),
+			},
+			wantErr: "migration 0002_users needs both up and down files",
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{
+				"0001_initial.down.sql": file("DROP TABLE incidents"),
+			},
+			wantErr: "migration 0001_initial needs both up and down files",
+		},
+		{
+			name: "two names",
+			fsys: fstest.MapFS{
+				"0001_initial.up.sql": file("CREATE TABLE incidents"),
+				"0001_other.down.sql": file("DROP TABLE incidents"),
+			},
+			wantErr: "migration 1 has two names",
+		},
+		{
+			name: "unexpected file",
+			fsys: fstest.MapFS{
+				"0001_initial.up.sql":   file("CREATE TABLE incidents"),
+				"0001_initial.down.sql": file("DROP TABLE incidents"),
+				"README.md":             file("docs"),
+			},
+			wantErr: "unexpected migration file README.md",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("expected migrations starting at version 1, got %v", migrations)
+	}
+}
+
+// TestMigrateLifecycle откатывает последнюю миграцию — запускать только на
+// тестовой базе.
+func TestMigrateLifecycle(t *testing.T) {
+	dbURL := config.GetDBURL()
+	if dbURL == "" {
+		t.Skip("DATABASE_URL not set, skipping integration test")
+	}
+
+	ctx := context.Background()
+	repo, err := NewPostgresRepo(dbURL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer repo.Close()
+
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	last := migrations[len(migrations)-1]
+
+	if _, err := repo.MigrateUp(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+
+	statuses, err := repo.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			t.Fatalf("migration %04d_%s is still pending after up", st.Version, st.Name)
+		}
+	}
+
+	reverted, err := repo.MigrateDown(ctx, 1)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != last.Version {
+		t.Fatalf("expected %s to be reverted, got %v", last, reverted)
+	}
+	statuses, err = repo.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, st := range statuses {
+		if st.Version == last.Version && st.AppliedAt != nil {
+			t.Fatalf("migration %s is still applied after down", last)
+		}
+	}
+
+	// реплики стартуют одновременно: миграцию применяет ровно одна
+	const replicas = 4
+	var wg sync.WaitGroup
+	applied := make([][]Migration, replicas)
+	errs := make([]error, replicas)
+	for i := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied[i], errs[i] = repo.MigrateUp(ctx)
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range replicas {
+		if errs[i] != nil {
+			t.Fatalf("concurrent up %d: %v", i, errs[i])
+		}
+		total += len(applied[i])
+	}
+	if total != 1 {
+		t.Fatalf("expected %s to be applied once, got %d applications", last, total)
+	}
+
+	// правка уже применённой миграции
+	if _, err := repo.db.ExecContext(ctx, `UPDATE schema_migrations SET checksum = 'changed' WHERE version = $1;`, last.Version); err != nil {
+		t.Fatalf("failed to corrupt checksum: %v", err)
+	}
+	defer repo.db.ExecContext(ctx, `UPDATE schema_migrations SET checksum = $1 WHERE version = $2;`, last.checksum(), last.Version)
+
+	if _, err := repo.MigrateUp(ctx); err == nil || !strings.Contains(err.Error(), "was changed after it had been applied") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS incident_history;
+DROP FUNCTION IF EXISTS incident_history_immutable();
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS locations_check;
+DROP TABLE IF EXISTS incidents;
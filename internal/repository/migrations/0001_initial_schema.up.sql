@@ -0,0 +1,98 @@
+-- Схема на момент перехода на миграции. Всё через IF NOT EXISTS: базы,
+-- созданные раньше при старте сервиса (CreateTables), принимают эту
+-- миграцию без изменений.
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id          SERIAL PRIMARY KEY,
+    tenant      TEXT        NOT NULL DEFAULT 'default',
+    title       TEXT        NOT NULL,
+    description TEXT        NOT NULL,
+    latitude    DOUBLE PRECISION NOT NULL,
+    longitude   DOUBLE PRECISION NOT NULL,
+    radius_m    INTEGER     NOT NULL,
+    active      BOOLEAN     NOT NULL DEFAULT TRUE,
+    version     BIGINT      NOT NULL DEFAULT 1,
+    deactivated_at TIMESTAMPTZ,
+    polygon     JSONB,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS locations_check (
+    id           SERIAL PRIMARY KEY,
+    tenant       TEXT         NOT NULL DEFAULT 'default',
+    user_id      INTEGER      NOT NULL,
+    latitude     DOUBLE PRECISION NOT NULL,
+    longitude    DOUBLE PRECISION NOT NULL,
+    incident_ids INTEGER[]    NOT NULL,
+    checked_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         SERIAL PRIMARY KEY,
+    tenant     TEXT        NOT NULL DEFAULT 'default',
+    name       TEXT        NOT NULL,
+    role       TEXT        NOT NULL,
+    prefix     TEXT        NOT NULL,
+    key_hash   TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+-- базы, созданные до появления тенантов: существующие данные
+-- достаются тенанту default
+ALTER TABLE incidents       ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE locations_check ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys        ADD COLUMN IF NOT EXISTS tenant TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS incidents_tenant_created_idx ON incidents (tenant, created_at DESC);
+CREATE INDEX IF NOT EXISTS locations_check_tenant_user_idx ON locations_check (tenant, user_id, checked_at DESC);
+
+-- статистика выбирает проверки тенанта за интервал времени
+CREATE INDEX IF NOT EXISTS locations_check_tenant_time_idx ON locations_check (tenant, checked_at);
+
+-- статистика инцидента ищет проверки по incident_ids @> ARRAY[id]
+CREATE INDEX IF NOT EXISTS locations_check_incident_ids_idx ON locations_check USING GIN (incident_ids);
+
+-- итоги отправки вебхуков; status_code пуст, если ответа не было
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id           BIGSERIAL PRIMARY KEY,
+    tenant       TEXT        NOT NULL,
+    incident_ids INTEGER[]   NOT NULL,
+    status_code  INTEGER,
+    success      BOOLEAN     NOT NULL,
+    error        TEXT        NOT NULL DEFAULT '',
+    delivered_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_incident_ids_idx ON webhook_deliveries USING GIN (incident_ids);
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- корзина: у деактивированных до появления колонки берём updated_at
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+UPDATE incidents SET deactivated_at = updated_at WHERE NOT active AND deactivated_at IS NULL;
+CREATE INDEX IF NOT EXISTS incidents_trash_idx ON incidents (deactivated_at) WHERE NOT active;
+
+ALTER TABLE incidents ADD COLUMN IF NOT EXISTS polygon JSONB;
+
+-- история неизменяема: UPDATE и DELETE запрещены триггером
+CREATE TABLE IF NOT EXISTS incident_history (
+    id          BIGSERIAL PRIMARY KEY,
+    tenant      TEXT        NOT NULL,
+    incident_id INTEGER     NOT NULL,
+    action      TEXT        NOT NULL,
+    actor       TEXT        NOT NULL,
+    version     BIGINT      NOT NULL,
+    changes     JSONB       NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS incident_history_incident_idx ON incident_history (tenant, incident_id, id);
+
+CREATE OR REPLACE FUNCTION incident_history_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'incident_history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER incident_history_immutable
+    BEFORE UPDATE OR DELETE ON incident_history
+    FOR EACH ROW EXECUTE FUNCTION incident_history_immutable();
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitPrefix = "ratelimit:"
+
+// tokenBucketScript атомарно пополняет и списывает токен. Время берётся из
+// Redis (TIME), поэтому у всех реплик API одни часы.
+// KEYS[1] — бакет, ARGV[1] — ёмкость, ARGV[2] — время полного пополнения, мкс.
+// Возвращает {allowed, remaining, retry_after_us, reset_us}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local rate = capacity / period
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(period / 1000) + 1000)
+
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+type TokenBucketResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	// через сколько бакет снова будет полон
+	Reset time.Duration
+}
+
+// TakeToken списывает токен из бакета key ёмкостью limit, который целиком
+// пополняется за period.
+func (s *Storage) TakeToken(ctx context.Context, key string, limit int, period time.Duration) (TokenBucketResult, error) {
+	res, err := tokenBucketScript.Run(ctx, s.cache.cache,
+		[]string{rateLimitPrefix + key}, limit, period.Microseconds()).Int64Slice()
+	if err != nil {
+		return TokenBucketResult{}, fmt.Errorf("token bucket: %w", err)
+	}
+	if len(res) != 4 {
+		return TokenBucketResult{}, fmt.Errorf("unexpected token bucket result length: %d", len(res))
+	}
+
+	return TokenBucketResult{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Microsecond,
+		Reset:      time.Duration(res[3]) * time.Microsecond,
+	}, nil
+}
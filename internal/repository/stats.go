@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"geo-notifications/internal/model"
+)
+
+// GetIncidentsStats считает проверки локаций тенанта за [from, to): итоги,
+// совпадения по инцидентам и ряд с шагом bucket. В ряду только интервалы,
+// где были проверки. Запросы идут в одном снимке базы, чтобы итоги
+// сходились с рядом.
+func (s *Storage) GetIncidentsStats(ctx context.Context, tenant string, from, to time.Time, bucket time.Duration) (*model.IncidentsStats, error) {
+	stats := &model.IncidentsStats{
+		From:      from,
+		To:        to,
+		Incidents: []model.IncidentMatchStats{},
+		Series:    []model.StatsPoint{},
+	}
+
+	queryTotals := `
+SELECT COUNT(*),
+       COUNT(*) FILTER (WHERE cardinality(incident_ids) > 0),
+       COUNT(DISTINCT user_id) FILTER (WHERE cardinality(incident_ids) > 0)
+FROM locations_check
+WHERE tenant = $1 AND checked_at >= $2 AND checked_at < $3;
+`
+	tx, err := s.repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, queryTotals, tenant, from, to).
+		Scan(&stats.TotalChecks, &stats.MatchingChecks, &stats.UserCount)
+	if err != nil {
+		return nil, err
+	}
+
+	queryIncidents := `
+SELECT incident_id, COUNT(*), COUNT(DISTINCT user_id)
+FROM locations_check, unnest(incident_ids) AS incident_id
+WHERE tenant = $1 AND checked_at >= $2 AND checked_at < $3
+GROUP BY incident_id
+ORDER BY COUNT(*) DESC, incident_id;
+`
+	rows, err := tx.QueryContext(ctx, queryIncidents, tenant, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m model.IncidentMatchStats
+		if err := rows.Scan(&m.IncidentID, &m.Matches, &m.UniqueUsers); err != nil {
+			return nil, err
+		}
+		stats.Incidents = append(stats.Incidents, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// начало интервала считаем от эпохи, а не date_trunc: так не зависим
+	// от часового пояса сессии
+	querySeries := `
+SELECT to_timestamp(floor(extract(epoch FROM checked_at) / $4) * $4) AS bucket,
+       COUNT(*),
+       COUNT(*) FILTER (WHERE cardinality(incident_ids) > 0),
+       COUNT(DISTINCT user_id) FILTER (WHERE cardinality(incident_ids) > 0)
+FROM locations_check
+WHERE tenant = $1 AND checked_at >= $2 AND checked_at < $3
+GROUP BY bucket
+ORDER BY bucket;
+`
+	seriesRows, err := tx.QueryContext(ctx, querySeries, tenant, from, to, bucket.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer seriesRows.Close()
+	for seriesRows.Next() {
+		var p model.StatsPoint
+		if err := seriesRows.Scan(&p.Start, &p.TotalChecks, &p.MatchingChecks, &p.UniqueUsers); err != nil {
+			return nil, err
+		}
+		p.Start = p.Start.UTC()
+		stats.Series = append(stats.Series, p)
+	}
+	if err := seriesRows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetIncidentStats считает охват инцидента по проверкам локаций и итоги
+// отправки вебхуков с ним. Ряд по часам охватывает не больше hourlySpan до
+// последнего совпадения; в нём только часы, где были совпадения. Поиск по
+// incident_ids @> идёт по GIN-индексу.
+func (s *Storage) GetIncidentStats(ctx context.Context, tenant string, id int64, hourlySpan time.Duration) (*model.IncidentStats, error) {
+	stats := &model.IncidentStats{IncidentID: id, Hourly: []model.ReachPoint{}}
+
+	tx, err := s.repo.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	queryTotals := `
+SELECT COUNT(*), COUNT(DISTINCT user_id), MIN(checked_at), MAX(checked_at)
+FROM locations_check
+WHERE tenant = $1 AND incident_ids @> ARRAY[$2::integer];
+`
+	var first, last sql.NullTime
+	err = tx.QueryRowContext(ctx, queryTotals, tenant, id).
+		Scan(&stats.Matches, &stats.UniqueUsers, &first, &last)
+	if err != nil {
+		return nil, err
+	}
+
+	if last.Valid {
+		firstAt, lastAt := first.Time.UTC(), last.Time.UTC()
+		stats.FirstMatchAt, stats.LastMatchAt = &firstAt, &lastAt
+
+		since := firstAt
+		if limit := lastAt.Truncate(time.Hour).Add(time.Hour - hourlySpan); since.Before(limit) {
+			since = limit
+		}
+		queryHourly := `
+SELECT to_timestamp(floor(extract(epoch FROM checked_at) / 3600) * 3600) AS bucket,
+       COUNT(*),
+       COUNT(DISTINCT user_id)
+FROM locations_check
+WHERE tenant = $1 AND incident_ids @> ARRAY[$2::integer] AND checked_at >= $3
+GROUP BY bucket
+ORDER BY bucket;
+`
+		rows, err := tx.QueryContext(ctx, queryHourly, tenant, id, since.Truncate(time.Hour))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var p model.ReachPoint
+			if err := rows.Scan(&p.Start, &p.Matches, &p.UniqueUsers); err != nil {
+				return nil, err
+			}
+			p.Start = p.Start.UTC()
+			stats.Hourly = append(stats.Hourly, p)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	queryDeliveries := `
+SELECT COUNT(*), COUNT(*) FILTER (WHERE success)
+FROM webhook_deliveries
+WHERE tenant = $1 AND incident_ids @> ARRAY[$2::integer];
+`
+	err = tx.QueryRowContext(ctx, queryDeliveries, tenant, id).
+		Scan(&stats.Webhooks.Attempts, &stats.Webhooks.Succeeded)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
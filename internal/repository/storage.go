@@ -4,12 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"geo-notifications/internal/config"
 	"geo-notifications/internal/model"
-	"math"
+	"geo-notifications/internal/tracing"
+	"math/rand"
+	"strings"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
@@ -29,7 +33,7 @@ type Storage struct {
 }
 
 func NewPostgresRepo(dbURL string) (*PostgresRepo, error) {
-	db, err := sql.Open("postgres", dbURL)
+	db, err := otelsql.Open("postgres", dbURL, sqlTraceOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +67,8 @@ func NewRedisCache(ctx context.Context, cfg config.RedisConfig) (*RedisCache, er
 		WriteTimeout: cfg.Timeout,
 	})
 
+	client.AddHook(redisTracingHook{})
+
 	if err := client.Ping(ctx).Err(); err != nil {
 		fmt.Printf("failed to connect to redis server: %s\n", err.Error())
 		return nil, err
@@ -86,74 +92,132 @@ func NewStorage(dbURL string, redisCfg config.RedisConfig) (*Storage, error) {
 	}, nil
 }
 
-func (s *Storage) CreateTables(ctx context.Context) error {
-	queryIncidents := `
-CREATE TABLE IF NOT EXISTS incidents (
-    id          SERIAL PRIMARY KEY,
-    title       TEXT        NOT NULL,
-    description TEXT        NOT NULL,
-    latitude    DOUBLE PRECISION NOT NULL,
-    longitude   DOUBLE PRECISION NOT NULL,
-    radius_m    INTEGER     NOT NULL,
-    active      BOOLEAN     NOT NULL DEFAULT TRUE,
-    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
-);
-`
-	if _, err := s.repo.db.ExecContext(ctx, queryIncidents); err != nil {
-		return fmt.Errorf("create table incidents: %w", err)
-	}
-
-	queryChecks := `
-CREATE TABLE IF NOT EXISTS locations_check (
-    id           SERIAL PRIMARY KEY,
-    user_id      INTEGER      NOT NULL,
-    latitude     DOUBLE PRECISION NOT NULL,
-    longitude    DOUBLE PRECISION NOT NULL,
-    incident_ids INTEGER[]    NOT NULL,
-    checked_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
-);
-`
-	if _, err := s.repo.db.ExecContext(ctx, queryChecks); err != nil {
-		return fmt.Errorf("create table locations_check: %w", err)
+// Create сохраняет инцидент и запись истории created от имени actor.
+func (s *Storage) Create(ctx context.Context, tenant, actor string, in *model.Incident) (int64, error) {
+	done := false
+	err := s.CreateEach(ctx, tenant, actor, func() (*model.Incident, error) {
+		if done {
+			return nil, nil
+		}
+		done = true
+		return in, nil
+	})
+	if err != nil {
+		return 0, err
 	}
-
-	return nil
+	return in.ID, nil
 }
 
-func (s *Storage) Create(ctx context.Context, in *model.Incident) (int64, error) {
+// CreateEach сохраняет одной транзакцией инциденты, которые по одному
+// отдаёт next, пока он не вернёт nil. Ошибка next откатывает всё, что
+// уже вставлено, и возвращается как есть.
+func (s *Storage) CreateEach(ctx context.Context, tenant, actor string, next func() (*model.Incident, error)) error {
 	query := `
-INSERT INTO incidents (title, description, latitude, longitude, radius_m, active)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at;
+INSERT INTO incidents (tenant, title, description, latitude, longitude, radius_m, active, polygon)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, version, created_at, updated_at;
 `
 
-	row := s.repo.db.QueryRowContext(ctx, query,
-		in.Title,
-		in.Description,
-		in.Latitude,
-		in.Longitude,
-		in.RadiusM,
-		in.Active,
-	)
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	if err := row.Scan(&in.ID, &in.CreatedAt, &in.UpdatedAt); err != nil {
-		return 0, err
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
 	}
-	return in.ID, nil
+	defer stmt.Close()
+
+	for {
+		in, err := next()
+		if err != nil {
+			return err
+		}
+		if in == nil {
+			break
+		}
+
+		polygon, err := polygonValue(in.Polygon)
+		if err != nil {
+			return err
+		}
+		row := stmt.QueryRowContext(ctx,
+			tenant,
+			in.Title,
+			in.Description,
+			in.Latitude,
+			in.Longitude,
+			in.RadiusM,
+			in.Active,
+			polygon,
+		)
+		if err := row.Scan(&in.ID, &in.Version, &in.CreatedAt, &in.UpdatedAt); err != nil {
+			return err
+		}
+
+		if err := insertHistory(ctx, tx, tenant, actor, model.HistoryCreated, nil, in); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+var (
+	ErrNotFound        = errors.New("incident not found")
+	ErrVersionMismatch = errors.New("incident version mismatch")
+)
+
+const incidentColumns = `id, title, description, latitude, longitude, radius_m, active, version, deactivated_at, polygon, created_at, updated_at`
+
+func scanIncident(row interface{ Scan(dest ...any) error }) (*model.Incident, error) {
+	var in model.Incident
+	var polygon []byte
+	if err := row.Scan(
+		&in.ID,
+		&in.Title,
+		&in.Description,
+		&in.Latitude,
+		&in.Longitude,
+		&in.RadiusM,
+		&in.Active,
+		&in.Version,
+		&in.DeactivatedAt,
+		&polygon,
+		&in.CreatedAt,
+		&in.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if polygon != nil {
+		if err := json.Unmarshal(polygon, &in.Polygon); err != nil {
+			return nil, fmt.Errorf("decode incident polygon: %w", err)
+		}
+	}
+	return &in, nil
 }
 
-func (s *Storage) GetList(ctx context.Context, page, pageSize int) ([]model.Incident, error) {
+// polygonValue — значение колонки polygon, NULL для круга.
+func polygonValue(p model.Polygon) (any, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (s *Storage) GetList(ctx context.Context, tenant string, page, pageSize int) ([]model.Incident, error) {
 	offset := (page - 1) * pageSize
 
 	query := fmt.Sprintf(`
-SELECT id, title, description, latitude, longitude, radius_m, active, created_at, updated_at
+SELECT %s
 FROM incidents
+WHERE tenant = $1
 ORDER BY created_at DESC
 LIMIT %d OFFSET %d;
-`, pageSize, offset)
+`, incidentColumns, pageSize, offset)
 
-	rows, err := s.repo.db.QueryContext(ctx, query)
+	rows, err := s.repo.db.QueryContext(ctx, query, tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -161,21 +225,11 @@ LIMIT %d OFFSET %d;
 
 	var result []model.Incident
 	for rows.Next() {
-		var in model.Incident
-		if err := rows.Scan(
-			&in.ID,
-			&in.Title,
-			&in.Description,
-			&in.Latitude,
-			&in.Longitude,
-			&in.RadiusM,
-			&in.Active,
-			&in.CreatedAt,
-			&in.UpdatedAt,
-		); err != nil {
+		in, err := scanIncident(rows)
+		if err != nil {
 			return nil, err
 		}
-		result = append(result, in)
+		result = append(result, *in)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -184,34 +238,96 @@ LIMIT %d OFFSET %d;
 	return result, nil
 }
 
-func (s *Storage) GetByID(ctx context.Context, id int64) (*model.Incident, error) {
-	query := `
-SELECT id, title, description, latitude, longitude, radius_m, active, created_at, updated_at
-FROM incidents
-WHERE id = $1;
-`
-	var in model.Incident
-	err := s.repo.db.QueryRowContext(ctx, query, id).Scan(
-		&in.ID,
-		&in.Title,
-		&in.Description,
-		&in.Latitude,
-		&in.Longitude,
-		&in.RadiusM,
-		&in.Active,
-		&in.CreatedAt,
-		&in.UpdatedAt,
-	)
+// EachActiveIncident построчно отдаёт активные инциденты тенанта в fn, не
+// загружая их в память целиком; ошибка fn прерывает обход.
+func (s *Storage) EachActiveIncident(ctx context.Context, tenant string, fn func(*model.Incident) error) error {
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE tenant = $1 AND active = TRUE ORDER BY id;`
+
+	rows, err := s.repo.db.QueryContext(ctx, query, tenant)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		in, err := scanIncident(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(in); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachIncident передаёт в fn инциденты тенанта из ids по одному, по
+// возрастанию id.
+func (s *Storage) EachIncident(ctx context.Context, tenant string, ids []int64, fn func(*model.Incident) error) error {
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE tenant = $1 AND id = ANY($2) ORDER BY id;`
+
+	rows, err := s.repo.db.QueryContext(ctx, query, tenant, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		in, err := scanIncident(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(in); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *Storage) GetByID(ctx context.Context, tenant string, id int64) (*model.Incident, error) {
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE id = $1 AND tenant = $2;`
+	in, err := scanIncident(s.repo.db.QueryRowContext(ctx, query, id, tenant))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &in, nil
+	return in, nil
+}
+
+// Update перезаписывает инцидент, если его версия равна ifVersion
+// (0 — любая), и увеличивает версию. Возвращает сохранённый инцидент и
+// false, если новые значения совпали с текущими и запись не менялась.
+// Ошибки — ErrNotFound и ErrVersionMismatch.
+func (s *Storage) Update(ctx context.Context, tenant, actor string, in *model.Incident, ifVersion int64) (*model.Incident, bool, error) {
+	return s.changeIncident(ctx, tenant, actor, in.ID, ifVersion, model.HistoryUpdated,
+		func(tx *sql.Tx, before *model.Incident) (*model.Incident, error) {
+			if sameFields(before, in) {
+				return nil, nil
+			}
+			return updateIncidentRow(ctx, tx, before.ID, in)
+		})
 }
 
-func (s *Storage) Update(ctx context.Context, in *model.Incident) error {
+// Patch блокирует инцидент и передаёт копию apply, который возвращает
+// новые значения всех полей (nil — менять нечего). Слияние и проверка
+// идут под блокировкой строки, поэтому параллельная правка не может
+// оказаться между чтением и записью. Ошибка apply возвращается как есть;
+// остальные — ErrNotFound и ErrVersionMismatch.
+func (s *Storage) Patch(ctx context.Context, tenant, actor string, id, ifVersion int64,
+	apply func(current model.Incident) (*model.Incident, error)) (*model.Incident, bool, error) {
+	return s.changeIncident(ctx, tenant, actor, id, ifVersion, model.HistoryUpdated,
+		func(tx *sql.Tx, before *model.Incident) (*model.Incident, error) {
+			after, err := apply(*before)
+			if err != nil || after == nil || sameFields(before, after) {
+				return nil, err
+			}
+			return updateIncidentRow(ctx, tx, before.ID, after)
+		})
+}
+
+func updateIncidentRow(ctx context.Context, tx *sql.Tx, id int64, in *model.Incident) (*model.Incident, error) {
 	query := `
 UPDATE incidents
 SET title = $1,
@@ -220,35 +336,54 @@ SET title = $1,
     longitude = $4,
     radius_m = $5,
     active = $6,
+    deactivated_at = CASE WHEN $6 THEN NULL ELSE COALESCE(deactivated_at, NOW()) END,
+    polygon = $7,
+    version = version + 1,
     updated_at = NOW()
-WHERE id = $7;
+WHERE id = $8
+RETURNING ` + incidentColumns + `;
 `
-	_, err := s.repo.db.ExecContext(ctx, query,
+	polygon, err := polygonValue(in.Polygon)
+	if err != nil {
+		return nil, err
+	}
+	return scanIncident(tx.QueryRowContext(ctx, query,
 		in.Title,
 		in.Description,
 		in.Latitude,
 		in.Longitude,
 		in.RadiusM,
 		in.Active,
-		in.ID,
-	)
-	return err
+		polygon,
+		id,
+	))
 }
 
-func (s *Storage) Deactivate(ctx context.Context, id int64) error {
-	query := `
+// Deactivate выключает инцидент. Возвращает инцидент и false, если он уже
+// был неактивен; ErrNotFound — инцидента нет.
+func (s *Storage) Deactivate(ctx context.Context, tenant, actor string, id int64) (*model.Incident, bool, error) {
+	return s.changeIncident(ctx, tenant, actor, id, 0, model.HistoryDeactivated,
+		func(tx *sql.Tx, before *model.Incident) (*model.Incident, error) {
+			if !before.Active {
+				return nil, nil
+			}
+			query := `
 UPDATE incidents
 SET active = FALSE,
+    deactivated_at = NOW(),
+    version = version + 1,
     updated_at = NOW()
-WHERE id = $1;
+WHERE id = $1
+RETURNING ` + incidentColumns + `;
 `
-	_, err := s.repo.db.ExecContext(ctx, query, id)
-	return err
+			return scanIncident(tx.QueryRowContext(ctx, query, id))
+		})
 }
 
-func (s *Storage) GetLocations(ctx context.Context, req model.LocationRequest) (model.LocationResponse, error) {
-	query := `SELECT id, title, description, latitude, longitude, radius_m, active, created_at, updated_at FROM incidents`
-	rows, err := s.repo.db.QueryContext(ctx, query)
+// GetLocations сопоставляет локацию только с инцидентами тенанта.
+func (s *Storage) GetLocations(ctx context.Context, tenant string, req model.LocationRequest) (model.LocationResponse, error) {
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE tenant = $1`
+	rows, err := s.repo.db.QueryContext(ctx, query, tenant)
 	if err != nil {
 		return model.LocationResponse{}, err
 	}
@@ -260,18 +395,8 @@ func (s *Storage) GetLocations(ctx context.Context, req model.LocationRequest) (
 	resp.Longitude = req.Longitude
 
 	for rows.Next() {
-		var in model.Incident
-		if err := rows.Scan(
-			&in.ID,
-			&in.Title,
-			&in.Description,
-			&in.Latitude,
-			&in.Longitude,
-			&in.RadiusM,
-			&in.Active,
-			&in.CreatedAt,
-			&in.UpdatedAt,
-		); err != nil {
+		in, err := scanIncident(rows)
+		if err != nil {
 			return model.LocationResponse{}, err
 		}
 
@@ -279,30 +404,34 @@ func (s *Storage) GetLocations(ctx context.Context, req model.LocationRequest) (
 			continue
 		}
 
-		// Условная метрика "близости" — сейчас просто манхэттен в градусах.
-		if math.Abs(in.Latitude-req.Latitude)+math.Abs(in.Longitude-req.Longitude) <= float64(in.RadiusM) {
+		if in.Covers(req.Longitude, req.Latitude) {
 			resp.LocationsIDS = append(resp.LocationsIDS, in.ID)
 		}
 	}
 
 	if len(resp.LocationsIDS) > 0 {
-		task := model.WebhookPayload{
-			UserID:       resp.UserID,
-			Latitude:     resp.Latitude,
-			Longitude:    resp.Longitude,
-			LocationsIDS: resp.LocationsIDS,
-			CheckedAt:    time.Now().UTC(),
+		task := model.WebhookTask{
+			WebhookPayload: model.WebhookPayload{
+				UserID:       resp.UserID,
+				Latitude:     resp.Latitude,
+				Longitude:    resp.Longitude,
+				LocationsIDS: resp.LocationsIDS,
+				CheckedAt:    time.Now().UTC(),
+				Tenant:       tenant,
+			},
+			TraceContext: tracing.Inject(ctx),
 		}
-		if err := s.EnqueueWebhookTask(ctx, task); err != nil {
+		if err := s.EnqueueWebhookTask(ctx, tenant, task); err != nil {
 			return model.LocationResponse{}, err
 		}
 	}
 
 	insertCheck := `
-INSERT INTO locations_check (user_id, latitude, longitude, incident_ids)
-VALUES ($1, $2, $3, $4);
+INSERT INTO locations_check (tenant, user_id, latitude, longitude, incident_ids)
+VALUES ($1, $2, $3, $4, $5);
 `
 	if _, err := s.repo.db.ExecContext(ctx, insertCheck,
+		tenant,
 		resp.UserID,
 		resp.Latitude,
 		resp.Longitude,
@@ -314,8 +443,81 @@ VALUES ($1, $2, $3, $4);
 	return resp, nil
 }
 
-func (s *Storage) BLPopWebhookTask(ctx context.Context, timeout time.Duration, key string) (string, error) {
-	res, err := s.cache.cache.BLPop(ctx, timeout, key).Result()
+// GetLastCheck возвращает последнюю проверку локации пользователя в виде
+// тела вебхука; nil — проверок ещё не было.
+func (s *Storage) GetLastCheck(ctx context.Context, tenant string, userID int64) (*model.WebhookPayload, error) {
+	query := `
+SELECT user_id, latitude, longitude, incident_ids, checked_at
+FROM locations_check
+WHERE tenant = $1 AND user_id = $2
+ORDER BY checked_at DESC, id DESC
+LIMIT 1;
+`
+	check := model.WebhookPayload{Tenant: tenant}
+	var ids pq.Int64Array
+	err := s.repo.db.QueryRowContext(ctx, query, tenant, userID).
+		Scan(&check.UserID, &check.Latitude, &check.Longitude, &ids, &check.CheckedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	check.LocationsIDS = ids
+	check.CheckedAt = check.CheckedAt.UTC()
+	return &check, nil
+}
+
+const (
+	webhookQueuePrefix = "webhook_queue:"
+	// множество тенантов, у которых когда-либо были задачи вебхуков
+	webhookTenantsKey = "webhook_queue_tenants"
+)
+
+// WebhookQueueKeys возвращает очереди вебхуков всех тенантов в случайном
+// порядке: BLPOP забирает задачу из первой непустой очереди, и без
+// перемешивания один тенант мог бы занять воркер целиком.
+func (s *Storage) WebhookQueueKeys(ctx context.Context) ([]string, error) {
+	tenants, err := s.cache.cache.SMembers(ctx, webhookTenantsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("smembers webhook tenants: %w", err)
+	}
+
+	keys := make([]string, 0, len(tenants))
+	for _, tenant := range tenants {
+		keys = append(keys, webhookQueuePrefix+tenant)
+	}
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	return keys, nil
+}
+
+// WebhookQueueDepths возвращает число задач в очереди вебхуков каждого тенанта.
+func (s *Storage) WebhookQueueDepths(ctx context.Context) (map[string]int64, error) {
+	keys, err := s.WebhookQueueKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := s.cache.cache.Pipeline()
+	lens := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		lens[i] = pipe.LLen(ctx, key)
+	}
+	if len(keys) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("llen webhook queues: %w", err)
+		}
+	}
+
+	depths := make(map[string]int64, len(keys))
+	for i, key := range keys {
+		depths[strings.TrimPrefix(key, webhookQueuePrefix)] = lens[i].Val()
+	}
+	return depths, nil
+}
+
+func (s *Storage) BLPopWebhookTask(ctx context.Context, timeout time.Duration, keys ...string) (string, error) {
+	res, err := s.cache.cache.BLPop(ctx, timeout, keys...).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return "", nil
@@ -330,32 +532,48 @@ func (s *Storage) BLPopWebhookTask(ctx context.Context, timeout time.Duration, k
 	return res[1], nil
 }
 
-func (s *Storage) EnqueueWebhookTask(ctx context.Context, task model.WebhookPayload) error {
+func (s *Storage) EnqueueWebhookTask(ctx context.Context, tenant string, task model.WebhookTask) error {
 	data, err := json.Marshal(task)
 	if err != nil {
 		return fmt.Errorf("marshal webhook task: %w", err)
 	}
 
-	const queueKey = "webhook_queue"
-
-	if err := s.cache.cache.RPush(ctx, queueKey, data).Err(); err != nil {
+	pipe := s.cache.cache.TxPipeline()
+	pipe.SAdd(ctx, webhookTenantsKey, tenant)
+	pipe.RPush(ctx, webhookQueuePrefix+tenant, data)
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("rpush webhook task: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Storage) GetUserCountLastMinutes(ctx context.Context, minutes int) (int, error) {
+// RecordWebhookDelivery сохраняет итог отправки вебхука. statusCode 0 —
+// ответа не было; успешной считается отправка с ответом 2xx.
+func (s *Storage) RecordWebhookDelivery(ctx context.Context, tenant string, incidentIDs []int64, statusCode int, deliveryErr string) error {
+	query := `
+INSERT INTO webhook_deliveries (tenant, incident_ids, status_code, success, error)
+VALUES ($1, $2, NULLIF($3, 0), $4, $5);
+`
+	success := statusCode >= 200 && statusCode < 300
+	if _, err := s.repo.db.ExecContext(ctx, query, tenant, pq.Array(incidentIDs), statusCode, success, deliveryErr); err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (s *Storage) GetUserCountLastMinutes(ctx context.Context, tenant string, minutes int) (int, error) {
 	query := `
 SELECT COUNT(DISTINCT user_id) AS user_count
 FROM locations_check
-WHERE checked_at >= NOW() - INTERVAL '%d minutes'
+WHERE tenant = $1
+  AND checked_at >= NOW() - INTERVAL '%d minutes'
   AND array_length(incident_ids, 1) > 0;
 `
 	q := fmt.Sprintf(query, minutes)
 
 	var count int
-	if err := s.repo.db.QueryRowContext(ctx, q).Scan(&count); err != nil {
+	if err := s.repo.db.QueryRowContext(ctx, q, tenant).Scan(&count); err != nil {
 		return 0, err
 	}
 	return count, nil
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"geo-notifications/internal/tracing"
+
+	"github.com/XSAM/otelsql"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var redisTracer = tracing.Tracer("repository/redis")
+
+// спаны запросов к базе пишутся только внутри трассы запроса или доставки
+var sqlTraceOptions = []otelsql.Option{
+	otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+	otelsql.WithSpanOptions(otelsql.SpanOptions{
+		OmitConnResetSession: true,
+		OmitConnPrepare:      true,
+		OmitRows:             true,
+		SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+			return tracing.Traced(ctx)
+		},
+	}),
+}
+
+// redisTracingHook пишет span на команду или конвейер Redis, если вызов
+// идёт внутри трассы.
+type redisTracingHook struct{}
+
+func (redisTracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisTracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !tracing.Traced(ctx) {
+			return next(ctx, cmd)
+		}
+		ctx, span := redisTracer.Start(ctx, "redis "+cmd.FullName(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameRedis,
+				semconv.DBOperationName(cmd.FullName()),
+			))
+		err := next(ctx, cmd)
+		tracing.End(span, redisError(err))
+		return err
+	}
+}
+
+func (redisTracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !tracing.Traced(ctx) {
+			return next(ctx, cmds)
+		}
+		names := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			names[i] = cmd.FullName()
+		}
+		ctx, span := redisTracer.Start(ctx, "redis pipeline",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNameRedis,
+				attribute.String("db.redis.commands", strings.Join(names, " ")),
+			))
+		err := next(ctx, cmds)
+		tracing.End(span, redisError(err))
+		return err
+	}
+}
+
+// redis.Nil — пустой результат, а не ошибка
+func redisError(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
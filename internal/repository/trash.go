@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"geo-notifications/internal/model"
+)
+
+// ErrIncidentActive — удалить насовсем можно только инцидент из корзины.
+var ErrIncidentActive = errors.New("incident is active")
+
+// Reactivate возвращает инцидент из корзины. false — он уже активен.
+func (s *Storage) Reactivate(ctx context.Context, tenant, actor string, id int64) (*model.Incident, bool, error) {
+	return s.changeIncident(ctx, tenant, actor, id, 0, model.HistoryReactivated,
+		func(tx *sql.Tx, before *model.Incident) (*model.Incident, error) {
+			if before.Active {
+				return nil, nil
+			}
+			query := `
+UPDATE incidents
+SET active = TRUE,
+    deactivated_at = NULL,
+    version = version + 1,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING ` + incidentColumns + `;
+`
+			return scanIncident(tx.QueryRowContext(ctx, query, id))
+		})
+}
+
+// GetTrash возвращает деактивированные инциденты тенанта, недавние первыми.
+func (s *Storage) GetTrash(ctx context.Context, tenant string, page, pageSize int) ([]model.Incident, error) {
+	query := fmt.Sprintf(`
+SELECT %s
+FROM incidents
+WHERE tenant = $1 AND NOT active
+ORDER BY deactivated_at DESC, id DESC
+LIMIT %d OFFSET %d;
+`, incidentColumns, pageSize, (page-1)*pageSize)
+
+	rows, err := s.repo.db.QueryContext(ctx, query, tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Incident
+	for rows.Next() {
+		in, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Purge удаляет деактивированный инцидент насовсем вместе с его id в
+// проверках локаций. История остаётся, в неё пишется запись purged.
+// Ошибки — ErrNotFound и ErrIncidentActive.
+func (s *Storage) Purge(ctx context.Context, tenant, actor string, id int64) (*model.Incident, error) {
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + incidentColumns + ` FROM incidents WHERE id = $1 AND tenant = $2 FOR UPDATE;`
+	in, err := scanIncident(tx.QueryRowContext(ctx, query, id, tenant))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if in.Active {
+		return nil, ErrIncidentActive
+	}
+
+	if err := purgeIncident(ctx, tx, tenant, actor, in); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// PurgedIncident — инцидент, удалённый PurgeTrash, вместе с его тенантом.
+type PurgedIncident struct {
+	Tenant   string
+	Incident *model.Incident
+}
+
+// PurgeTrash удаляет до limit инцидентов всех тенантов, лежащих в корзине
+// с момента раньше before. Возвращает удалённые инциденты.
+func (s *Storage) PurgeTrash(ctx context.Context, actor string, before time.Time, limit int) ([]PurgedIncident, error) {
+	tx, err := s.repo.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED — реплики не ждут друг друга и не удаляют одно и то же
+	query := fmt.Sprintf(`
+SELECT tenant, %s
+FROM incidents
+WHERE NOT active AND deactivated_at < $1
+ORDER BY deactivated_at
+LIMIT %d
+FOR UPDATE SKIP LOCKED;
+`, incidentColumns, limit)
+	rows, err := tx.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []PurgedIncident
+	for rows.Next() {
+		var tenant string
+		in, err := scanIncident(scanFunc(func(dest ...any) error {
+			return rows.Scan(append([]any{&tenant}, dest...)...)
+		}))
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, PurgedIncident{Tenant: tenant, Incident: in})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, e := range list {
+		if err := purgeIncident(ctx, tx, e.Tenant, actor, e.Incident); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func purgeIncident(ctx context.Context, tx *sql.Tx, tenant, actor string, in *model.Incident) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM incidents WHERE id = $1;`, in.ID); err != nil {
+		return fmt.Errorf("delete incident: %w", err)
+	}
+
+	query := `
+UPDATE locations_check
+SET incident_ids = array_remove(incident_ids, $1)
+WHERE tenant = $2 AND incident_ids @> ARRAY[$1]::integer[];
+`
+	if _, err := tx.ExecContext(ctx, query, in.ID, tenant); err != nil {
+		return fmt.Errorf("purge incident from location checks: %w", err)
+	}
+
+	// иначе удалённый id остался бы в статистике доставок
+	query = `
+UPDATE webhook_deliveries
+SET incident_ids = array_remove(incident_ids, $1)
+WHERE tenant = $2 AND incident_ids @> ARRAY[$1]::integer[];
+`
+	if _, err := tx.ExecContext(ctx, query, in.ID, tenant); err != nil {
+		return fmt.Errorf("purge incident from webhook deliveries: %w", err)
+	}
+
+	return insertHistory(ctx, tx, tenant, actor, model.HistoryPurged, in, nil)
+}
+
+type scanFunc func(dest ...any) error
+
+func (f scanFunc) Scan(dest ...any) error {
+	return f(dest...)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+)
+
+// SignAlertsToken выдаёт токен подписки на алерты пользователя тенанта:
+// hex(HMAC-SHA256(secret, "<tenant>:<user_id>")), для тенанта default —
+// hex(HMAC-SHA256(secret, user_id)). Используется бэкендом, который
+// отдаёт токен мобильному клиенту.
+func SignAlertsToken(secret, tenant string, userID int64) string {
+	msg := strconv.FormatInt(userID, 10)
+	if tenant != auth.DefaultTenant {
+		msg = tenant + ":" + msg
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyAlertsToken принимает HMAC-токен или JWT пользователя того же
+// тенанта с subject, равным user_id.
+func (is *incidentService) verifyAlertsToken(ctx context.Context, tenant string, userID int64, token string) bool {
+	if token == "" {
+		return false
+	}
+	if is.cfg.AlertsSecret != "" {
+		expected := SignAlertsToken(is.cfg.AlertsSecret, tenant, userID)
+		if hmac.Equal([]byte(expected), []byte(token)) {
+			return true
+		}
+	}
+	if is.cfg.JWT != nil && strings.Count(token, ".") == 2 {
+		principal, err := is.cfg.JWT.Verify(ctx, token)
+		if err != nil {
+			return false
+		}
+		subjectID, _, err := principal.SubjectUserID()
+		return err == nil && subjectID == userID && principal.Tenant == tenant
+	}
+	return false
+}
+
+// SubscribeUserAlerts подписывает клиента на совпадения его локации и на
+// события инцидентов, в зону которых попадает его последняя проверка.
+func (is *incidentService) SubscribeUserAlerts(ctx context.Context, tenant string, userID int64, token string) (<-chan model.Alert, error) {
+	if !auth.ValidTenant(tenant) || !is.verifyAlertsToken(ctx, tenant, userID, token) {
+		return nil, ErrInvalidToken
+	}
+
+	live := is.events.subscribe(tenant)
+
+	last, err := is.storage.GetLastCheck(ctx, tenant, userID)
+	if err != nil {
+		is.events.unsubscribe(live)
+		is.log(ctx).WithError(err).Error("failed to load last location check")
+		return nil, err
+	}
+
+	out := make(chan model.Alert)
+	go func() {
+		defer close(out)
+		defer is.events.unsubscribe(live)
+
+		for {
+			var ev model.Event
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-live:
+				if !ok {
+					return
+				}
+				ev = e
+			}
+
+			var alert *model.Alert
+			switch {
+			case ev.Type == model.EventLocationMatched:
+				if ev.Match == nil || ev.Match.UserID != userID {
+					continue
+				}
+				last = ev.Match
+				alert = &model.Alert{Type: ev.Type, WebhookPayload: *ev.Match}
+			case ev.Incident != nil:
+				if last == nil || incidentAlert(ev, *last) == nil {
+					continue
+				}
+				// пользователь мог уйти из зоны проверкой без совпадений —
+				// сверяемся с последней проверкой
+				check, err := is.storage.GetLastCheck(ctx, tenant, userID)
+				if err != nil {
+					is.log(ctx).WithError(err).Warn("failed to refresh last location check")
+				} else if check != nil {
+					last = check
+				}
+				alert = incidentAlert(ev, *last)
+			}
+			if alert == nil {
+				continue
+			}
+
+			select {
+			case out <- *alert:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// incidentAlert строит алерт о событии инцидента для пользователя с
+// последней проверкой check или возвращает nil, если событие его не
+// касается. Новый или восстановленный инцидент касается, если точка
+// проверки в его зоне; изменённый, деактивированный или удалённый — ещё и
+// если он совпал при этой проверке: изменение могло вывести точку из зоны.
+func incidentAlert(ev model.Event, check model.WebhookPayload) *model.Alert {
+	inc := ev.Incident
+	covers := inc.Covers(check.Longitude, check.Latitude)
+	matched := slices.Contains(check.LocationsIDS, inc.ID)
+
+	switch ev.Type {
+	case model.EventIncidentCreated, model.EventIncidentReactivated:
+		if !inc.Active || !covers {
+			return nil
+		}
+	case model.EventIncidentUpdated, model.EventIncidentDeactivated, model.EventIncidentPurged:
+		if !covers && !matched {
+			return nil
+		}
+	default:
+		return nil
+	}
+
+	// locations_ids — инциденты, в зоне которых пользователь после события
+	ids := make([]int64, 0, len(check.LocationsIDS)+1)
+	for _, id := range check.LocationsIDS {
+		if id != inc.ID {
+			ids = append(ids, id)
+		}
+	}
+	if inc.Active && covers && ev.Type != model.EventIncidentPurged {
+		ids = append(ids, inc.ID)
+	}
+	check.LocationsIDS = ids
+	return &model.Alert{Type: ev.Type, WebhookPayload: check, Incident: inc}
+}
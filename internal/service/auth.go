@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+)
+
+func (is *incidentService) AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error) {
+	if key == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if is.cfg.BootstrapAPIKey != "" &&
+		subtle.ConstantTimeCompare([]byte(key), []byte(is.cfg.BootstrapAPIKey)) == 1 {
+		return &auth.Principal{Name: "bootstrap", Role: auth.RoleAdmin, Tenant: auth.DefaultTenant, AllTenants: true}, nil
+	}
+
+	k, err := is.storage.GetActiveAPIKeyByHash(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to look up api key")
+		return nil, err
+	}
+	if k == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	role, ok := auth.ParseRole(k.Role)
+	if !ok {
+		is.log(ctx).WithField("api_key_id", k.ID).Warn("api key has unknown role")
+		return nil, ErrInvalidAPIKey
+	}
+	return &auth.Principal{KeyID: k.ID, Name: k.Name, Role: role, Tenant: k.Tenant}, nil
+}
+
+func (is *incidentService) AuthenticateBearerToken(ctx context.Context, token string) (*auth.Principal, error) {
+	if is.cfg.JWT == nil || token == "" {
+		return nil, ErrInvalidBearer
+	}
+
+	principal, err := is.cfg.JWT.Verify(ctx, token)
+	if err != nil {
+		if !errors.Is(err, auth.ErrInvalidJWT) && !errors.Is(err, auth.ErrExpiredJWT) && !errors.Is(err, auth.ErrUnknownKey) {
+			is.log(ctx).WithError(err).Error("failed to verify bearer token")
+		}
+		return nil, ErrInvalidBearer
+	}
+	return principal, nil
+}
+
+// keysScope — тенант, ключами которого может управлять клиент; пустая
+// строка — любого тенанта (bootstrap-ключ или отключённая аутентификация).
+func keysScope(ctx context.Context) string {
+	if p := auth.PrincipalFromContext(ctx); p == nil || p.AllTenants {
+		return ""
+	}
+	return auth.TenantFromContext(ctx)
+}
+
+// actor — автор изменения для истории инцидентов: API-ключ (key:<id>),
+// пользователь JWT (jwt:<tenant>:<sub>) или anonymous при отключённой
+// аутентификации.
+func actor(ctx context.Context) string {
+	if id := auth.PrincipalFromContext(ctx).ClientID(); id != "" {
+		return id
+	}
+	return "anonymous"
+}
+
+// CreateAPIKey выпускает ключ для tenant; пустой tenant — тенант клиента.
+func (is *incidentService) CreateAPIKey(ctx context.Context, name, role, tenant string) (*model.APIKey, error) {
+	if tenant == "" {
+		tenant = auth.TenantFromContext(ctx)
+	}
+
+	var v validator
+	v.check(name != "", "name", "is required")
+	_, ok := auth.ParseRole(role)
+	v.check(ok, "role", "must be one of admin, dispatcher, reporter, viewer")
+	v.check(auth.ValidTenant(tenant), "tenant", "must match [a-z0-9][a-z0-9_-]{0,62}")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if scope := keysScope(ctx); scope != "" && scope != tenant {
+		return nil, ErrTenantForbidden
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	k := &model.APIKey{
+		Name:   name,
+		Role:   role,
+		Tenant: tenant,
+		Prefix: key[:auth.APIKeyDisplayLen],
+	}
+	if err := is.storage.CreateAPIKey(ctx, k, auth.HashAPIKey(key)); err != nil {
+		is.log(ctx).WithError(err).Error("failed to create api key")
+		return nil, err
+	}
+	k.Key = key
+	return k, nil
+}
+
+func (is *incidentService) ListAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	keys, err := is.storage.ListAPIKeys(ctx, keysScope(ctx))
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to list api keys")
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (is *incidentService) RevokeAPIKey(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return NewValidationError(FieldError{Field: "id", Message: "must be positive"})
+	}
+
+	found, err := is.storage.RevokeAPIKey(ctx, keysScope(ctx), id)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to revoke api key")
+		return err
+	}
+	if !found {
+		return NewNotFoundError("api_key", id)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind — класс ошибки сервисного слоя; по нему транспорт (HTTP, gRPC)
+// выбирает статус ответа.
+type ErrorKind int
+
+const (
+	KindValidation ErrorKind = iota + 1
+	KindNotFound
+	KindConflict
+	KindUnauthorized
+	KindForbidden
+	KindRateLimited
+	KindPrecondition
+)
+
+// FieldError описывает ошибку конкретного поля запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error — типизированная ошибка сервиса со стабильным машиночитаемым кодом.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Fields  []FieldError
+	// RateLimit — состояние лимита для KindRateLimited
+	RateLimit *RateLimitStatus
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return e.Message + ": " + strings.Join(parts, "; ")
+}
+
+var (
+	ErrInvalidEventID  = &Error{Kind: KindValidation, Code: "invalid_event_id", Message: "invalid event id"}
+	ErrInvalidToken    = &Error{Kind: KindUnauthorized, Code: "invalid_token", Message: "invalid token"}
+	ErrInvalidAPIKey   = &Error{Kind: KindUnauthorized, Code: "invalid_api_key", Message: "invalid or revoked API key"}
+	ErrInvalidBearer   = &Error{Kind: KindUnauthorized, Code: "invalid_bearer_token", Message: "invalid or expired bearer token"}
+	ErrTenantForbidden = &Error{Kind: KindForbidden, Code: "tenant_forbidden", Message: "cannot manage api keys of another tenant"}
+	ErrVersionMismatch = &Error{Kind: KindPrecondition, Code: "version_mismatch", Message: "incident was modified concurrently"}
+	ErrIncidentActive  = &Error{Kind: KindConflict, Code: "incident_active", Message: "only deactivated incidents can be purged"}
+)
+
+func NewValidationError(fields ...FieldError) *Error {
+	return &Error{
+		Kind:    KindValidation,
+		Code:    "validation_failed",
+		Message: "validation failed",
+		Fields:  fields,
+	}
+}
+
+func NewNotFoundError(resource string, id int64) *Error {
+	return &Error{
+		Kind:    KindNotFound,
+		Code:    resource + "_not_found",
+		Message: fmt.Sprintf("%s %d not found", resource, id),
+	}
+}
+
+func NewConflictError(code, message string) *Error {
+	return &Error{
+		Kind:    KindConflict,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func NewRateLimitError(st *RateLimitStatus) *Error {
+	return &Error{
+		Kind:      KindRateLimited,
+		Code:      "rate_limited",
+		Message:   "rate limit exceeded",
+		RateLimit: st,
+	}
+}
+
+// validator копит ошибки полей, чтобы вернуть их все разом.
+type validator struct {
+	fields []FieldError
+}
+
+func (v *validator) check(ok bool, field, message string) {
+	if !ok {
+		v.fields = append(v.fields, FieldError{Field: field, Message: message})
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return NewValidationError(v.fields...)
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+)
+
+// размер буфера подписчика; медленного подписчика отключаем, он
+// переподключится с Last-Event-ID
+const subscriberBuffer = 64
+
+// eventHub раздаёт события, полученные из Redis, локальным подписчикам
+// (SSE-клиентам этой реплики). Подписчик получает события только своего
+// тенанта.
+type eventHub struct {
+	mu   sync.Mutex
+	subs map[chan model.Event]string
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan model.Event]string)}
+}
+
+func (h *eventHub) subscribe(tenant string) chan model.Event {
+	ch := make(chan model.Event, subscriberBuffer)
+	h.mu.Lock()
+	h.subs[ch] = tenant
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan model.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+func (h *eventHub) broadcast(ev model.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, tenant := range h.subs {
+		if ev.Tenant != tenant {
+			continue
+		}
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// RunEvents читает события из Redis pub/sub и раздаёт их подписчикам до
+// отмены ctx. При обрыве подписки переподключается. После остановки
+// закрывает все подписки, чтобы стримы не держали graceful shutdown.
+func (is *incidentService) RunEvents(ctx context.Context) {
+	defer is.events.closeAll()
+
+	for {
+		events, err := is.storage.SubscribeEvents(ctx)
+		if err != nil {
+			is.log(ctx).WithError(err).Error("failed to subscribe to events")
+		} else {
+			for ev := range events {
+				is.events.broadcast(ev)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (is *incidentService) SubscribeEvents(ctx context.Context, lastEventID string) (<-chan model.Event, error) {
+	tenant := auth.TenantFromContext(ctx)
+
+	// подписываемся до чтения буфера, чтобы не потерять события между ними
+	live := is.events.subscribe(tenant)
+
+	var backlog []model.Event
+	if lastEventID != "" {
+		if _, _, ok := parseEventID(lastEventID); !ok {
+			is.events.unsubscribe(live)
+			return nil, ErrInvalidEventID
+		}
+		var err error
+		backlog, err = is.storage.EventsSince(ctx, lastEventID)
+		if err != nil {
+			is.events.unsubscribe(live)
+			is.log(ctx).WithError(err).Error("failed to read events backlog")
+			return nil, err
+		}
+	}
+
+	out := make(chan model.Event)
+	go func() {
+		defer close(out)
+		defer is.events.unsubscribe(live)
+
+		last := lastEventID
+		send := func(ev model.Event) bool {
+			if last != "" && !eventIDAfter(ev.ID, last) {
+				return true
+			}
+			select {
+			case out <- ev:
+				last = ev.ID
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, ev := range backlog {
+			// буфер общий для всех тенантов
+			if ev.Tenant != tenant {
+				continue
+			}
+			if !send(ev) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-live:
+				if !ok || !send(ev) {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (is *incidentService) publish(ctx context.Context, ev model.Event) {
+	ev.Tenant = auth.TenantFromContext(ctx)
+	ev.OccurredAt = time.Now().UTC()
+	if err := is.storage.PublishEvent(ctx, &ev); err != nil {
+		is.log(ctx).WithError(err).WithField("event", ev.Type).Warn("failed to publish event")
+	}
+}
+
+// parseEventID разбирает ID записи Redis Stream вида "<ms>-<seq>".
+func parseEventID(id string) (ms, seq uint64, ok bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
+
+// eventIDAfter сообщает, идёт ли событие a строго после b.
+func eventIDAfter(a, b string) bool {
+	aMs, aSeq, okA := parseEventID(a)
+	bMs, bSeq, okB := parseEventID(b)
+	if !okA || !okB {
+		return true
+	}
+	if aMs != bMs {
+		return aMs > bMs
+	}
+	return aSeq > bSeq
+}
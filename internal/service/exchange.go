@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/tracing"
+)
+
+const (
+	// сколько инцидентов можно импортировать одним запросом
+	maxImportItems = 10000
+	// после стольких ошибок разбор файла прекращается
+	maxImportErrors = 100
+)
+
+// IncidentReader отдаёт импортируемые инциденты по одному, не загружая
+// файл целиком. fields — ошибки текущего элемента: импорт продолжается,
+// чтобы собрать ошибки всех элементов. err — ошибка чтения, прерывающая
+// импорт; io.EOF — конец данных.
+type IncidentReader interface {
+	Next() (in *model.Incident, fields []FieldError, err error)
+}
+
+// ImportIncidents создаёт инциденты из r одной транзакцией: если хоть один
+// не проходит проверку, не создаётся ни один. Ошибки полей возвращаются
+// для всех элементов сразу, с префиксом индекса ("[3].title"). В памяти
+// держится только текущий элемент и id созданных; транзакция открыта, пока
+// r читает данные, поэтому время чтения ограничивает вызывающий.
+func (is *incidentService) ImportIncidents(ctx context.Context, r IncidentReader) (_ *model.ImportResult, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.ImportIncidents")
+	defer func() { tracing.End(span, err) }()
+
+	var (
+		ids     []int64
+		pending *model.Incident
+		fields  []FieldError
+		readErr error
+		index   int
+	)
+	next := func() (*model.Incident, error) {
+		// id предыдущему элементу CreateEach присваивает после его возврата
+		if pending != nil {
+			ids = append(ids, pending.ID)
+			pending = nil
+		}
+		for {
+			in, itemFields, err := r.Next()
+			if errors.Is(err, io.EOF) {
+				if len(fields) > 0 {
+					return nil, NewValidationError(fields...)
+				}
+				if index == 0 {
+					return nil, NewValidationError(FieldError{Field: "items", Message: "must not be empty"})
+				}
+				return nil, nil
+			}
+			if err != nil {
+				readErr = err
+				return nil, err
+			}
+
+			i := index
+			index++
+			if index > maxImportItems {
+				return nil, NewValidationError(FieldError{Field: "items", Message: fmt.Sprintf("must contain at most %d items", maxImportItems)})
+			}
+
+			if in != nil {
+				var svcErr *Error
+				if errors.As(validateIncident(in), &svcErr) {
+					itemFields = append(itemFields, svcErr.Fields...)
+				}
+			}
+			for _, f := range itemFields {
+				fields = append(fields, FieldError{Field: fmt.Sprintf("[%d].%s", i, f.Field), Message: f.Message})
+			}
+			if len(fields) >= maxImportErrors {
+				return nil, NewValidationError(fields...)
+			}
+			// после первой ошибки только проверяем остальное
+			if len(fields) > 0 || in == nil {
+				continue
+			}
+
+			in.Active = true
+			normalizePolygon(in)
+			pending = in
+			return in, nil
+		}
+	}
+
+	tenant := auth.TenantFromContext(ctx)
+	err = is.storage.CreateEach(ctx, tenant, actor(ctx), next)
+	if err != nil {
+		var svcErr *Error
+		if !errors.As(err, &svcErr) && err != readErr && ctx.Err() == nil {
+			is.log(ctx).WithError(err).Error("failed to import incidents")
+		}
+		return nil, err
+	}
+
+	// события — только после коммита; инциденты перечитываются из базы
+	// по одному, а не копятся во время импорта
+	err = is.storage.EachIncident(ctx, tenant, ids, func(in *model.Incident) error {
+		is.publish(ctx, model.Event{Type: model.EventIncidentCreated, Incident: in})
+		return nil
+	})
+	if err != nil {
+		is.log(ctx).WithError(err).Warn("failed to publish imported incidents")
+	}
+	return &model.ImportResult{Created: len(ids), IDs: ids}, nil
+}
+
+// ExportIncidents передаёт в fn активные инциденты тенанта по одному.
+func (is *incidentService) ExportIncidents(ctx context.Context, fn func(*model.Incident) error) (err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.ExportIncidents")
+	defer func() { tracing.End(span, err) }()
+
+	if err := is.storage.EachActiveIncident(ctx, auth.TenantFromContext(ctx), fn); err != nil {
+		is.log(ctx).WithError(err).Error("failed to export incidents")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"geo-notifications/internal/model"
+)
+
+// сколько /readyz ждёт ответа каждой зависимости
+const readinessPingTimeout = 2 * time.Second
+
+// Lifecycle — состояние процесса для /readyz: сердцебиение воркера
+// вебхуков и флаг начавшейся остановки. Методы безопасны для nil.
+type Lifecycle struct {
+	heartbeat    atomic.Int64 // unix nano последнего цикла воркера
+	shuttingDown atomic.Bool
+}
+
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// Beat отмечает, что воркер вебхуков жив.
+func (l *Lifecycle) Beat() {
+	if l != nil {
+		l.heartbeat.Store(time.Now().UnixNano())
+	}
+}
+
+// LastBeat — время последнего Beat, нулевое, если его не было.
+func (l *Lifecycle) LastBeat() time.Time {
+	if l == nil {
+		return time.Time{}
+	}
+	if ns := l.heartbeat.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// StartShutdown переводит /readyz в 503, чтобы балансировщик перестал
+// слать запросы до остановки сервера.
+func (l *Lifecycle) StartShutdown() {
+	if l != nil {
+		l.shuttingDown.Store(true)
+	}
+}
+
+func (l *Lifecycle) ShuttingDown() bool {
+	return l != nil && l.shuttingDown.Load()
+}
+
+// Readiness проверяет Postgres и Redis с замером задержки и собирает
+// глубину очереди вебхуков и возраст сердцебиения воркера.
+func (is *incidentService) Readiness(ctx context.Context) *model.Readiness {
+	r := &model.Readiness{
+		Status: model.ReadinessReady,
+		Checks: map[string]model.DependencyCheck{
+			"postgres": is.pingDependency(ctx, "postgres", is.storage.PingDB),
+			"redis":    is.pingDependency(ctx, "redis", is.storage.PingRedis),
+		},
+	}
+	for _, c := range r.Checks {
+		if c.Status != "ok" {
+			r.Status = model.ReadinessNotReady
+		}
+	}
+	if is.cfg.Lifecycle.ShuttingDown() {
+		r.Status = model.ReadinessShuttingDown
+	}
+
+	if r.Checks["redis"].Status == "ok" {
+		pingCtx, cancel := context.WithTimeout(ctx, readinessPingTimeout)
+		depths, err := is.storage.WebhookQueueDepths(pingCtx)
+		cancel()
+		if err != nil {
+			is.log(ctx).WithError(err).Warn("failed to get webhook queue depth")
+		} else {
+			var total int64
+			for _, d := range depths {
+				total += d
+			}
+			r.WebhookQueueDepth = &total
+		}
+	}
+	if last := is.cfg.Lifecycle.LastBeat(); !last.IsZero() {
+		age := time.Since(last).Seconds()
+		r.WorkerHeartbeatAgeSeconds = &age
+	}
+	return r
+}
+
+func (is *incidentService) pingDependency(ctx context.Context, name string, ping func(context.Context) error) model.DependencyCheck {
+	ctx, cancel := context.WithTimeout(ctx, readinessPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ping(ctx)
+	c := model.DependencyCheck{
+		Status:    "ok",
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		is.log(ctx).WithError(err).WithField("dependency", name).Warn("readiness check failed")
+		c.Status = "error"
+	}
+	return c
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/repository"
+	"geo-notifications/internal/tracing"
+)
+
+const (
+	// ячейка скрывается, если в ней меньше разных пользователей
+	defaultHeatmapMinUsers = 5
+	// больше ячеек за раз не отдаём: карте они не нужны, а базе дороги
+	maxHeatmapCells = 10000
+
+	defaultGeohashPrecision = 6
+	maxGeohashPrecision     = 9
+	defaultSquarePrecision  = 2
+	maxSquarePrecision      = 4
+)
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// HeatmapQuery — параметры тепловой карты. Precision для geohash — длина
+// геохеша (1–9), для square — число знаков после запятой в размере ячейки
+// в градусах (1–4: от 0.1° до 0.0001°); 0 — значение по умолчанию.
+// MinUsers не может быть меньше настроенного порога.
+type HeatmapQuery struct {
+	From      time.Time
+	To        time.Time
+	Grid      string
+	Precision int
+	// minLon, minLat, maxLon, maxLat; nil — весь мир
+	BBox         *[4]float64
+	OnlyMatching bool
+	MinUsers     int
+}
+
+// GetHeatmap собирает проверки локаций тенанта по ячейкам сетки.
+func (is *incidentService) GetHeatmap(ctx context.Context, q HeatmapQuery) (_ *model.Heatmap, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetHeatmap")
+	defer func() { tracing.End(span, err) }()
+
+	if q.Grid == "" {
+		q.Grid = model.HeatmapGeohash
+	}
+	if q.Precision == 0 {
+		q.Precision = defaultGeohashPrecision
+		if q.Grid == model.HeatmapSquare {
+			q.Precision = defaultSquarePrecision
+		}
+	}
+	minUsers := is.cfg.HeatmapMinUsers
+	if minUsers <= 0 {
+		minUsers = defaultHeatmapMinUsers
+	}
+	if q.MinUsers == 0 {
+		q.MinUsers = minUsers
+	}
+	bbox := [4]float64{-180, -90, 180, 90}
+	if q.BBox != nil {
+		bbox = *q.BBox
+	}
+
+	length := q.To.Sub(q.From)
+	var v validator
+	v.check(length > 0, "from", "must be before to")
+	v.check(length <= maxStatsRange, "from", "range must not exceed 31 days")
+	switch q.Grid {
+	case model.HeatmapGeohash:
+		v.check(q.Precision >= 1 && q.Precision <= maxGeohashPrecision, "precision", "must be between 1 and 9 for geohash")
+	case model.HeatmapSquare:
+		v.check(q.Precision >= 1 && q.Precision <= maxSquarePrecision, "precision", "must be between 1 and 4 for square")
+	default:
+		v.check(false, "grid", "must be geohash or square")
+	}
+	v.check(bbox[0] >= -180 && bbox[2] <= 180 && bbox[0] < bbox[2] &&
+		bbox[1] >= -90 && bbox[3] <= 90 && bbox[1] < bbox[3],
+		"bbox", "must be minLon,minLat,maxLon,maxLat within coordinate ranges")
+	v.check(q.MinUsers >= minUsers, "min_users", "must be at least "+strconv.Itoa(minUsers))
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	rows, cols := heatmapGridSize(q.Grid, q.Precision)
+	cells, suppressed, err := is.storage.GetHeatmapCells(ctx, auth.TenantFromContext(ctx), repository.HeatmapFilter{
+		From:         q.From,
+		To:           q.To,
+		Rows:         rows,
+		Cols:         cols,
+		BBox:         bbox,
+		OnlyMatching: q.OnlyMatching,
+		MinUsers:     q.MinUsers,
+		Limit:        maxHeatmapCells,
+	})
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to get heatmap cells")
+		return nil, err
+	}
+	if len(cells) > maxHeatmapCells {
+		return nil, NewValidationError(FieldError{
+			Field:   "precision",
+			Message: "result exceeds 10000 cells, lower precision or narrow bbox or time range",
+		})
+	}
+
+	dLat, dLon := 180/float64(rows), 360/float64(cols)
+	for i := range cells {
+		c := &cells[i]
+		minLat, minLon := -90+float64(c.Row)*dLat, -180+float64(c.Col)*dLon
+		c.Bounds = [4]float64{minLon, minLat, minLon + dLon, minLat + dLat}
+		if q.Grid == model.HeatmapGeohash {
+			c.ID = geohashCell(c.Row, c.Col, q.Precision)
+		} else {
+			c.ID = strconv.FormatFloat(minLat, 'f', q.Precision, 64) + "," +
+				strconv.FormatFloat(minLon, 'f', q.Precision, 64)
+		}
+	}
+
+	return &model.Heatmap{
+		Grid:       q.Grid,
+		Precision:  q.Precision,
+		From:       q.From,
+		To:         q.To,
+		MinUsers:   q.MinUsers,
+		Suppressed: suppressed,
+		Cells:      cells,
+	}, nil
+}
+
+// heatmapGridSize — на сколько частей делятся широта и долгота. Геохеш
+// длины p — это 5p бит, через один долготы и широты, начиная с долготы.
+func heatmapGridSize(grid string, precision int) (rows, cols int64) {
+	if grid == model.HeatmapGeohash {
+		bits := 5 * precision
+		return 1 << (bits / 2), 1 << ((bits + 1) / 2)
+	}
+	scale := int64(math.Pow10(precision))
+	return 180 * scale, 360 * scale
+}
+
+// geohashCell кодирует ячейку сетки геохеша в строку.
+func geohashCell(row, col int64, precision int) string {
+	latBits, lonBits := 5*precision/2, (5*precision+1)/2
+	out := make([]byte, precision)
+	for i := range out {
+		var ch byte
+		for b := 0; b < 5; b++ {
+			ch <<= 1
+			if (i*5+b)%2 == 0 {
+				lonBits--
+				ch |= byte(col >> lonBits & 1)
+			} else {
+				latBits--
+				ch |= byte(row >> latBits & 1)
+			}
+		}
+		out[i] = geohashBase32[ch]
+	}
+	return string(out)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"geo-notifications/internal/model"
+)
+
+const (
+	defaultIdempotencyTTL = 24 * time.Hour
+	// сколько ключ держится за незавершённым запросом: если реплика упала
+	// посреди запроса, клиент сможет повторить его после этого срока
+	idempotencyPendingTTL = time.Minute
+	maxIdempotencyKeyLen  = 255
+)
+
+var (
+	ErrIdempotencyKeyReused  = NewConflictError("idempotency_key_reused", "Idempotency-Key was already used with a different request")
+	ErrIdempotencyInProgress = NewConflictError("idempotency_in_progress", "a request with this Idempotency-Key is still in progress")
+)
+
+// BeginIdempotentRequest занимает ключ под запрос с отпечатком fingerprint.
+// nil-запись — запрос новый и его нужно выполнить, завершив потом
+// CompleteIdempotentRequest или AbortIdempotentRequest; иначе возвращается
+// сохранённый ответ на повтор.
+func (is *incidentService) BeginIdempotentRequest(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, error) {
+	if key == "" || len(key) > maxIdempotencyKeyLen {
+		return nil, NewValidationError(FieldError{Field: "Idempotency-Key", Message: "must be 1-255 characters"})
+	}
+
+	pending := model.IdempotencyRecord{Fingerprint: fingerprint}
+	reserved, stored, err := is.storage.ReserveIdempotencyKey(ctx, key, pending, idempotencyPendingTTL)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to reserve idempotency key")
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	if stored.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !stored.Done {
+		return nil, ErrIdempotencyInProgress
+	}
+	return stored, nil
+}
+
+func (is *incidentService) CompleteIdempotentRequest(ctx context.Context, key string, rec model.IdempotencyRecord) error {
+	rec.Done = true
+	ttl := is.cfg.IdempotencyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	if err := is.storage.SaveIdempotencyRecord(ctx, key, rec, ttl); err != nil {
+		is.log(ctx).WithError(err).Error("failed to save idempotent response")
+		return err
+	}
+	return nil
+}
+
+// AbortIdempotentRequest освобождает ключ, чтобы запрос можно было повторить.
+func (is *incidentService) AbortIdempotentRequest(ctx context.Context, key string) {
+	if err := is.storage.DeleteIdempotencyKey(ctx, key); err != nil {
+		is.log(ctx).WithError(err).Warn("failed to release idempotency key")
+	}
+}
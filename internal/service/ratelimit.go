@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"geo-notifications/internal/auth"
+)
+
+// RateLimit — не больше Limit запросов за Period (token bucket ёмкостью
+// Limit, который полностью пополняется за Period). Нулевое значение —
+// без ограничений.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+func (l RateLimit) Enabled() bool {
+	return l.Limit > 0 && l.Period > 0
+}
+
+// ParseRateLimit разбирает лимит вида "20/s", "600/m" или "1000/h";
+// пустая строка и "off" выключают ограничение.
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return RateLimit{}, nil
+	}
+
+	countStr, unit, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q: expected <count>/<s|m|h>", s)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: count must be a positive integer", s)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return RateLimit{}, fmt.Errorf("rate limit %q: unknown unit %q", s, unit)
+	}
+	return RateLimit{Limit: count, Period: period}, nil
+}
+
+// RateLimits — лимиты на клиента (API-ключ, пользователя JWT или IP) по
+// маршрутам и отдельный лимит проверок локации на user_id.
+type RateLimits struct {
+	// Default — для маршрутов без своего лимита
+	Default RateLimit
+	// Routes — по пути маршрута HTTP (/api/v1/location/check)
+	Routes map[string]RateLimit
+	// User — проверки локации одного user_id, с любых клиентов
+	User RateLimit
+}
+
+// ParseRateLimitRoutes разбирает список "<path>=<limit>,<path>=<limit>".
+func ParseRateLimitRoutes(s string) (map[string]RateLimit, error) {
+	routes := make(map[string]RateLimit)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		path, limitStr, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate limit route %q: expected <path>=<limit>", item)
+		}
+		limit, err := ParseRateLimit(limitStr)
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.TrimSpace(path)] = limit
+	}
+	return routes, nil
+}
+
+// RateLimitStatus — состояние бакета после запроса, для заголовков ответа.
+type RateLimitStatus struct {
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+func (is *incidentService) routeLimit(route string) RateLimit {
+	if l, ok := is.cfg.RateLimits.Routes[route]; ok {
+		return l
+	}
+	return is.cfg.RateLimits.Default
+}
+
+// AllowRequest списывает запрос клиента client из лимита маршрута route.
+// nil-статус — лимит для маршрута не задан. При исчерпании лимита
+// возвращается ошибка KindRateLimited.
+func (is *incidentService) AllowRequest(ctx context.Context, route, client string) (*RateLimitStatus, error) {
+	return is.takeToken(ctx, "route:"+route+":"+client, is.routeLimit(route))
+}
+
+// allowUserCheck ограничивает проверки локации одного пользователя тенанта,
+// сколькими бы клиентами он ни ходил.
+func (is *incidentService) allowUserCheck(ctx context.Context, userID int64) error {
+	key := "user:" + auth.TenantFromContext(ctx) + ":" + strconv.FormatInt(userID, 10)
+	_, err := is.takeToken(ctx, key, is.cfg.RateLimits.User)
+	return err
+}
+
+func (is *incidentService) takeToken(ctx context.Context, key string, limit RateLimit) (*RateLimitStatus, error) {
+	if !limit.Enabled() {
+		return nil, nil
+	}
+
+	res, err := is.storage.TakeToken(ctx, key, limit.Limit, limit.Period)
+	if err != nil {
+		// недоступный Redis не должен класть API — пропускаем запрос
+		is.log(ctx).WithError(err).Warn("rate limiter is unavailable")
+		return nil, nil
+	}
+
+	st := &RateLimitStatus{
+		Limit:      limit.Limit,
+		Remaining:  res.Remaining,
+		Reset:      res.Reset,
+		RetryAfter: res.RetryAfter,
+	}
+	if !res.Allowed {
+		return st, NewRateLimitError(st)
+	}
+	return st, nil
+}
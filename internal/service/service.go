@@ -2,28 +2,78 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/logging"
+	"geo-notifications/internal/metrics"
 	"geo-notifications/internal/model"
 	"geo-notifications/internal/repository"
+	"geo-notifications/internal/tracing"
 
 	"github.com/sirupsen/logrus"
 )
 
+var tracer = tracing.Tracer("service")
+
 type IncidentService interface {
 	HealthCheck(ctx context.Context) *HealthError
+	Readiness(ctx context.Context) *model.Readiness
 	CreateIncident(ctx context.Context, inc *model.Incident) error
 	GetItemsList(ctx context.Context, page, pageSize int) ([]model.Incident, error)
 	GetIncidentByID(ctx context.Context, id int64) (*model.Incident, error)
 	GetUserStats(ctx context.Context, minutes int) (int, error)
-	UpdateIncident(ctx context.Context, in *model.Incident) error
+	GetIncidentsStats(ctx context.Context, q StatsQuery) (*model.IncidentsStats, error)
+	GetIncidentStats(ctx context.Context, id int64) (*model.IncidentStats, error)
+	GetHeatmap(ctx context.Context, q HeatmapQuery) (*model.Heatmap, error)
+	UpdateIncident(ctx context.Context, in *model.Incident, ifVersion int64) (*model.Incident, error)
+	PatchIncident(ctx context.Context, id int64, patch *model.IncidentPatch, ifVersion int64) (*model.Incident, error)
 	DeactivateIncident(ctx context.Context, id int64) error
+	GetIncidentHistory(ctx context.Context, id int64) ([]model.IncidentChange, error)
+	ReactivateIncident(ctx context.Context, id int64) (*model.Incident, error)
+	PurgeIncident(ctx context.Context, id int64) error
+	GetTrash(ctx context.Context, page, pageSize int) ([]model.Incident, error)
+	ImportIncidents(ctx context.Context, r IncidentReader) (*model.ImportResult, error)
+	ExportIncidents(ctx context.Context, fn func(*model.Incident) error) error
 	CheckLocations(ctx context.Context, req model.LocationRequest) (model.LocationResponse, error)
+	SubscribeEvents(ctx context.Context, lastEventID string) (<-chan model.Event, error)
+	SubscribeUserAlerts(ctx context.Context, tenant string, userID int64, token string) (<-chan model.Alert, error)
+	AuthenticateAPIKey(ctx context.Context, key string) (*auth.Principal, error)
+	AuthenticateBearerToken(ctx context.Context, token string) (*auth.Principal, error)
+	CreateAPIKey(ctx context.Context, name, role, tenant string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	AllowRequest(ctx context.Context, route, client string) (*RateLimitStatus, error)
+	BeginIdempotentRequest(ctx context.Context, key, fingerprint string) (*model.IdempotencyRecord, error)
+	CompleteIdempotentRequest(ctx context.Context, key string, rec model.IdempotencyRecord) error
+	AbortIdempotentRequest(ctx context.Context, key string)
+}
+
+// Config — настройки сервиса из окружения.
+type Config struct {
+	// секрет для токенов WebSocket-алертов (ALERTS_TOKEN_SECRET)
+	AlertsSecret string
+	// ключ администратора, не хранящийся в базе (ADMIN_API_KEY)
+	BootstrapAPIKey string
+	// проверка JWT пользователей; nil — bearer-токены не принимаются
+	JWT *auth.JWTVerifier
+	// лимиты запросов; нулевое значение — без ограничений
+	RateLimits RateLimits
+	// сколько хранится ответ на запрос с Idempotency-Key (IDEMPOTENCY_TTL)
+	IdempotencyTTL time.Duration
+	// порог k-анонимности тепловой карты (HEATMAP_MIN_USERS)
+	HeatmapMinUsers int
+	// сердцебиение воркера и флаг остановки для /readyz; может быть nil
+	Lifecycle *Lifecycle
 }
 
 type incidentService struct {
 	storage *repository.Storage
 	logger  *logrus.Logger
+	events  *eventHub
+	cfg     Config
 }
 
 type HealthError struct {
@@ -31,13 +81,20 @@ type HealthError struct {
 	RedisError error
 }
 
-func NewIncidentService(storage *repository.Storage, logger *logrus.Logger) *incidentService {
+func NewIncidentService(storage *repository.Storage, logger *logrus.Logger, cfg Config) *incidentService {
 	return &incidentService{
 		storage: storage,
 		logger:  logger,
+		events:  newEventHub(),
+		cfg:     cfg,
 	}
 }
 
+// log — логгер запроса из ctx (с request_id), вне запроса — общий.
+func (is *incidentService) log(ctx context.Context) *logrus.Entry {
+	return logging.FromContext(ctx, is.logger)
+}
+
 func (is *incidentService) HealthCheck(ctx context.Context) *HealthError {
 	var h HealthError
 
@@ -54,102 +111,321 @@ func (is *incidentService) HealthCheck(ctx context.Context) *HealthError {
 	return nil
 }
 
-func (is *incidentService) CreateIncident(ctx context.Context, req *model.Incident) error {
-	if req.Title == "" {
-		return fmt.Errorf("title is required")
+func validateIncident(in *model.Incident) error {
+	var v validator
+	checkIncident(&v, in)
+	return v.err()
+}
+
+// validateChangedIncident — проверка для PUT и PATCH: без полигона зона —
+// круг, и с нулевым радиусом инцидент не совпал бы ни с одной проверкой
+// (так бывает, если полигон удалили, а радиус не задали).
+func validateChangedIncident(in *model.Incident) error {
+	var v validator
+	checkIncident(&v, in)
+	v.check(len(in.Polygon) > 0 || in.RadiusM > 0, "radius_m", "must be positive for an incident without polygon")
+	return v.err()
+}
+
+func checkIncident(v *validator, in *model.Incident) {
+	v.check(in.Title != "", "title", "is required")
+	v.check(in.RadiusM >= 0, "radius_m", "must not be negative")
+	validatePolygon(v, in.Polygon)
+}
+
+// validatePolygon проверяет полигон по правилам GeoJSON: кольца замкнуты,
+// в каждом не меньше четырёх точек, координаты в допустимых пределах.
+func validatePolygon(v *validator, p model.Polygon) {
+	for i, ring := range p {
+		field := fmt.Sprintf("polygon[%d]", i)
+		if len(ring) < 4 {
+			v.check(false, field, "must have at least 4 positions")
+			continue
+		}
+		v.check(ring[0] == ring[len(ring)-1], field, "must be closed")
+		for _, pt := range ring {
+			if pt[0] < -180 || pt[0] > 180 || pt[1] < -90 || pt[1] > 90 {
+				v.check(false, field, "has coordinates out of range")
+				break
+			}
+		}
 	}
-	if req.RadiusM < 0 {
-		return fmt.Errorf("radius must be positive")
+}
+
+// normalizePolygon ставит центр инцидента с полигоном в центроид, а радиус
+// обнуляет: зона задаётся полигоном.
+func normalizePolygon(in *model.Incident) {
+	if len(in.Polygon) == 0 {
+		return
+	}
+	in.Longitude, in.Latitude = in.Polygon.Centroid()
+	in.RadiusM = 0
+}
+
+func (is *incidentService) CreateIncident(ctx context.Context, req *model.Incident) (err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.CreateIncident")
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateIncident(req); err != nil {
+		return err
 	}
 
 	req.Active = true
+	normalizePolygon(req)
 
-	_, err := is.storage.Create(ctx, req)
+	_, err = is.storage.Create(ctx, auth.TenantFromContext(ctx), actor(ctx), req)
 	if err != nil {
-		is.logger.WithError(err).Warn("failed to create incident")
+		is.log(ctx).WithError(err).Warn("failed to create incident")
 		return err
 	}
+
+	inc := *req
+	is.publish(ctx, model.Event{Type: model.EventIncidentCreated, Incident: &inc})
 	return nil
 }
 
-func (is *incidentService) GetItemsList(ctx context.Context, page, pageSize int) ([]model.Incident, error) {
-	if page < 1 || pageSize < 1 {
-		return nil, fmt.Errorf("invalid pagination parameters: page=%d, pageSize=%d", page, pageSize)
+func (is *incidentService) GetItemsList(ctx context.Context, page, pageSize int) (_ []model.Incident, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetItemsList")
+	defer func() { tracing.End(span, err) }()
+
+	var v validator
+	v.check(page >= 1, "page", "must be positive")
+	v.check(pageSize >= 1, "page_size", "must be positive")
+	if err := v.err(); err != nil {
+		return nil, err
 	}
 
-	results, err := is.storage.GetList(ctx, page, pageSize)
+	results, err := is.storage.GetList(ctx, auth.TenantFromContext(ctx), page, pageSize)
 	if err != nil {
-		is.logger.WithError(err).Info("error while getting list of incidents")
+		is.log(ctx).WithError(err).Info("error while getting list of incidents")
 		return nil, err
 	}
 
 	return results, nil
 }
 
-func (is *incidentService) GetIncidentByID(ctx context.Context, id int64) (*model.Incident, error) {
+func (is *incidentService) GetIncidentByID(ctx context.Context, id int64) (_ *model.Incident, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetIncidentByID")
+	defer func() { tracing.End(span, err) }()
+
 	if id <= 0 {
-		return nil, fmt.Errorf("invalid id: %d", id)
+		return nil, NewValidationError(FieldError{Field: "id", Message: "must be positive"})
 	}
 
-	incident, err := is.storage.GetByID(ctx, id)
+	incident, err := is.storage.GetByID(ctx, auth.TenantFromContext(ctx), id)
 	if err != nil {
-		is.logger.WithError(err).Error("error getting incident by id")
+		is.log(ctx).WithError(err).Error("error getting incident by id")
 		return nil, err
 	}
+	if incident == nil {
+		return nil, NewNotFoundError("incident", id)
+	}
 	return incident, nil
 }
 
-func (is *incidentService) GetUserStats(ctx context.Context, minutes int) (int, error) {
+func (is *incidentService) GetUserStats(ctx context.Context, minutes int) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetUserStats")
+	defer func() { tracing.End(span, err) }()
+
 	if minutes <= 0 {
-		return 0, fmt.Errorf("invalid stats window: %d", minutes)
+		return 0, NewValidationError(FieldError{Field: "minutes", Message: "must be positive"})
 	}
 
-	count, err := is.storage.GetUserCountLastMinutes(ctx, minutes)
+	count, err := is.storage.GetUserCountLastMinutes(ctx, auth.TenantFromContext(ctx), minutes)
 	if err != nil {
-		is.logger.WithError(err).Error("failed to get user stats")
+		is.log(ctx).WithError(err).Error("failed to get user stats")
 		return 0, err
 	}
 	return count, nil
 }
 
-func (is *incidentService) UpdateIncident(ctx context.Context, in *model.Incident) error {
+// UpdateIncident перезаписывает инцидент, если его текущая версия равна
+// ifVersion (0 — без проверки), иначе возвращает ErrVersionMismatch.
+// Возвращает сохранённый инцидент; если значения не изменились, версия не
+// растёт и событие не публикуется.
+func (is *incidentService) UpdateIncident(ctx context.Context, in *model.Incident, ifVersion int64) (_ *model.Incident, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.UpdateIncident")
+	defer func() { tracing.End(span, err) }()
+
 	if in.ID <= 0 {
-		return fmt.Errorf("invalid id: %d", in.ID)
+		return nil, NewValidationError(FieldError{Field: "id", Message: "must be positive"})
 	}
-	if in.Title == "" {
-		return fmt.Errorf("title is required")
+	if err := validateChangedIncident(in); err != nil {
+		return nil, err
 	}
-	if in.RadiusM < 0 {
-		return fmt.Errorf("radius must be positive")
+	normalizePolygon(in)
+
+	updated, changed, err := is.storage.Update(ctx, auth.TenantFromContext(ctx), actor(ctx), in, ifVersion)
+	if err != nil {
+		return nil, is.storageError(ctx, err, in.ID, "failed to update incident")
 	}
 
-	if err := is.storage.Update(ctx, in); err != nil {
-		is.logger.WithError(err).Error("failed to update incident")
+	if changed {
+		inc := *updated
+		is.publish(ctx, model.Event{Type: model.EventIncidentUpdated, Incident: &inc})
+	}
+	return updated, nil
+}
+
+// storageError переводит ошибки условных UPDATE хранилища в ошибки сервиса.
+func (is *incidentService) storageError(ctx context.Context, err error, id int64, msg string) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return NewNotFoundError("incident", id)
+	case errors.Is(err, repository.ErrVersionMismatch):
+		return ErrVersionMismatch
+	case errors.Is(err, repository.ErrIncidentActive):
+		return ErrIncidentActive
+	case errors.As(err, new(*Error)):
+		// ошибка проверки, вернувшаяся из-под блокировки строки
+		return err
+	default:
+		is.log(ctx).WithError(err).Error(msg)
 		return err
 	}
-	return nil
 }
 
-func (is *incidentService) DeactivateIncident(ctx context.Context, id int64) error {
+// PatchIncident применяет patch к текущему инциденту, проверяет результат
+// целиком и сохраняет его. Всё это идёт под блокировкой строки, так что
+// параллельный PUT или PATCH не будет перезаписан данными из старого
+// снимка. Пустой patch ничего не меняет.
+func (is *incidentService) PatchIncident(ctx context.Context, id int64, patch *model.IncidentPatch, ifVersion int64) (_ *model.Incident, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.PatchIncident")
+	defer func() { tracing.End(span, err) }()
+
 	if id <= 0 {
-		return fmt.Errorf("invalid id: %d", id)
+		return nil, NewValidationError(FieldError{Field: "id", Message: "must be positive"})
 	}
 
-	if err := is.storage.Deactivate(ctx, id); err != nil {
-		is.logger.WithError(err).Error("failed to deactivate incident")
-		return err
+	updated, changed, err := is.storage.Patch(ctx, auth.TenantFromContext(ctx), actor(ctx), id, ifVersion,
+		func(merged model.Incident) (*model.Incident, error) {
+			applyPatch(&merged, patch)
+			if err := validateChangedIncident(&merged); err != nil {
+				return nil, err
+			}
+			// центр и радиус следуют за полигоном
+			normalizePolygon(&merged)
+			return &merged, nil
+		})
+	if err != nil {
+		return nil, is.storageError(ctx, err, id, "failed to patch incident")
+	}
+
+	if changed {
+		inc := *updated
+		is.publish(ctx, model.Event{Type: model.EventIncidentUpdated, Incident: &inc})
+	}
+	return updated, nil
+}
+
+func applyPatch(in *model.Incident, patch *model.IncidentPatch) {
+	if patch.Title != nil {
+		in.Title = *patch.Title
+	}
+	if patch.Description != nil {
+		in.Description = *patch.Description
+	}
+	if patch.Latitude != nil {
+		in.Latitude = *patch.Latitude
+	}
+	if patch.Longitude != nil {
+		in.Longitude = *patch.Longitude
+	}
+	if patch.RadiusM != nil {
+		in.RadiusM = *patch.RadiusM
+	}
+	if patch.Active != nil {
+		in.Active = *patch.Active
+	}
+	if patch.Polygon != nil {
+		in.Polygon = *patch.Polygon
+	}
+}
+
+// DeactivateIncident выключает инцидент; повторная деактивация ничего не
+// меняет и событие не публикует.
+func (is *incidentService) DeactivateIncident(ctx context.Context, id int64) (err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.DeactivateIncident")
+	defer func() { tracing.End(span, err) }()
+
+	if id <= 0 {
+		return NewValidationError(FieldError{Field: "id", Message: "must be positive"})
+	}
+
+	inc, changed, err := is.storage.Deactivate(ctx, auth.TenantFromContext(ctx), actor(ctx), id)
+	if err != nil {
+		return is.storageError(ctx, err, id, "failed to deactivate incident")
+	}
+
+	if changed {
+		is.publish(ctx, model.Event{Type: model.EventIncidentDeactivated, Incident: inc})
 	}
 	return nil
 }
 
-func (is *incidentService) CheckLocations(ctx context.Context, req model.LocationRequest) (model.LocationResponse, error) {
+// GetIncidentHistory возвращает историю изменений инцидента. У инцидентов,
+// созданных до появления истории, она может быть пустой.
+func (is *incidentService) GetIncidentHistory(ctx context.Context, id int64) (_ []model.IncidentChange, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetIncidentHistory")
+	defer func() { tracing.End(span, err) }()
+
+	if id <= 0 {
+		return nil, NewValidationError(FieldError{Field: "id", Message: "must be positive"})
+	}
+
+	tenant := auth.TenantFromContext(ctx)
+	history, err := is.storage.GetIncidentHistory(ctx, tenant, id)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to load incident history")
+		return nil, err
+	}
+	if len(history) > 0 {
+		return history, nil
+	}
+
+	incident, err := is.storage.GetByID(ctx, tenant, id)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("error getting incident by id")
+		return nil, err
+	}
+	if incident == nil {
+		return nil, NewNotFoundError("incident", id)
+	}
+	return []model.IncidentChange{}, nil
+}
+
+func (is *incidentService) CheckLocations(ctx context.Context, req model.LocationRequest) (_ model.LocationResponse, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.CheckLocations")
+	defer func() { tracing.End(span, err) }()
+
 	if req.UserID <= 0 {
-		return model.LocationResponse{}, fmt.Errorf("invalid user_id: %d", req.UserID)
+		return model.LocationResponse{}, NewValidationError(FieldError{Field: "user_id", Message: "must be positive"})
 	}
-	locations, err := is.storage.GetLocations(ctx, req)
+	if err := is.allowUserCheck(ctx, req.UserID); err != nil {
+		return model.LocationResponse{}, err
+	}
+	tenant := auth.TenantFromContext(ctx)
+	locations, err := is.storage.GetLocations(ctx, tenant, req)
 	if err != nil {
-		is.logger.WithError(err).Error("failed to get locations")
+		is.log(ctx).WithError(err).Error("failed to get locations")
 		return model.LocationResponse{}, err
 	}
+
+	if len(locations.LocationsIDS) == 0 {
+		metrics.LocationChecks.WithLabelValues("miss").Inc()
+	} else {
+		metrics.LocationChecks.WithLabelValues("match").Inc()
+		is.publish(ctx, model.Event{
+			Type: model.EventLocationMatched,
+			Match: &model.WebhookPayload{
+				UserID:       locations.UserID,
+				Latitude:     locations.Latitude,
+				Longitude:    locations.Longitude,
+				LocationsIDS: locations.LocationsIDS,
+				CheckedAt:    time.Now().UTC(),
+				Tenant:       tenant,
+			},
+		})
+	}
 	return locations, nil
 }
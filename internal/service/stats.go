@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/tracing"
+)
+
+const (
+	// самый длинный интервал статистики
+	maxStatsRange = 31 * 24 * time.Hour
+	// самый длинный интервал для ряда по минутам
+	maxMinuteStatsRange = 24 * time.Hour
+	// до какой длины интервала ряд по умолчанию строится по минутам
+	autoMinuteStatsRange = 3 * time.Hour
+)
+
+// StatsQuery — интервал статистики [From, To) и шаг ряда. Пустой Bucket —
+// минута для интервалов до трёх часов, иначе час.
+type StatsQuery struct {
+	From   time.Time
+	To     time.Time
+	Bucket string
+}
+
+// GetIncidentsStats возвращает статистику проверок локаций тенанта. Ряд
+// непрерывный: интервалы без проверок отдаются с нулями.
+func (is *incidentService) GetIncidentsStats(ctx context.Context, q StatsQuery) (_ *model.IncidentsStats, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetIncidentsStats")
+	defer func() { tracing.End(span, err) }()
+
+	length := q.To.Sub(q.From)
+	if q.Bucket == "" {
+		q.Bucket = model.StatsByHour
+		if length <= autoMinuteStatsRange {
+			q.Bucket = model.StatsByMinute
+		}
+	}
+
+	var v validator
+	v.check(length > 0, "from", "must be before to")
+	v.check(length <= maxStatsRange, "from", "range must not exceed 31 days")
+	v.check(q.Bucket == model.StatsByMinute || q.Bucket == model.StatsByHour, "bucket", "must be minute or hour")
+	v.check(q.Bucket != model.StatsByMinute || length <= maxMinuteStatsRange, "bucket", "minute series are limited to 24 hours")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	step := time.Hour
+	if q.Bucket == model.StatsByMinute {
+		step = time.Minute
+	}
+
+	stats, err := is.storage.GetIncidentsStats(ctx, auth.TenantFromContext(ctx), q.From, q.To, step)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to get incidents stats")
+		return nil, err
+	}
+	stats.Bucket = q.Bucket
+	stats.Series = fillStatsSeries(stats.Series, q.From, q.To, step)
+	return stats, nil
+}
+
+// fillStatsSeries дополняет ряд нулевыми точками для интервалов без
+// проверок; points отсортированы по Start.
+func fillStatsSeries(points []model.StatsPoint, from, to time.Time, step time.Duration) []model.StatsPoint {
+	filled := make([]model.StatsPoint, 0, int(to.Sub(from)/step)+1)
+	i := 0
+	for start := from.UTC().Truncate(step); start.Before(to); start = start.Add(step) {
+		if i < len(points) && points[i].Start.Equal(start) {
+			filled = append(filled, points[i])
+			i++
+			continue
+		}
+		filled = append(filled, model.StatsPoint{Start: start})
+	}
+	return filled
+}
+
+// GetIncidentStats возвращает охват инцидента, в том числе деактивированного.
+// Часовой ряд непрерывный, не длиннее maxStatsRange до последнего совпадения.
+func (is *incidentService) GetIncidentStats(ctx context.Context, id int64) (_ *model.IncidentStats, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetIncidentStats")
+	defer func() { tracing.End(span, err) }()
+
+	if id <= 0 {
+		return nil, NewValidationError(FieldError{Field: "id", Message: "must be positive"})
+	}
+
+	tenant := auth.TenantFromContext(ctx)
+	incident, err := is.storage.GetByID(ctx, tenant, id)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("error getting incident by id")
+		return nil, err
+	}
+	if incident == nil {
+		return nil, NewNotFoundError("incident", id)
+	}
+
+	stats, err := is.storage.GetIncidentStats(ctx, tenant, id, maxStatsRange)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to get incident stats")
+		return nil, err
+	}
+	if stats.Webhooks.Attempts > 0 {
+		rate := float64(stats.Webhooks.Succeeded) / float64(stats.Webhooks.Attempts)
+		stats.Webhooks.SuccessRate = &rate
+	}
+	if stats.LastMatchAt != nil {
+		to := stats.LastMatchAt.Truncate(time.Hour).Add(time.Hour)
+		from := to.Add(-maxStatsRange)
+		if stats.FirstMatchAt.After(from) {
+			from = *stats.FirstMatchAt
+		}
+		stats.Hourly = fillReachSeries(stats.Hourly, from, to)
+	}
+	return stats, nil
+}
+
+// fillReachSeries дополняет часовой ряд охвата нулевыми точками.
+func fillReachSeries(points []model.ReachPoint, from, to time.Time) []model.ReachPoint {
+	filled := make([]model.ReachPoint, 0, int(to.Sub(from)/time.Hour)+1)
+	i := 0
+	for start := from.UTC().Truncate(time.Hour); start.Before(to); start = start.Add(time.Hour) {
+		if i < len(points) && points[i].Start.Equal(start) {
+			filled = append(filled, points[i])
+			i++
+			continue
+		}
+		filled = append(filled, model.ReachPoint{Start: start})
+	}
+	return filled
+}
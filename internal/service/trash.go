@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"geo-notifications/internal/auth"
+	"geo-notifications/internal/model"
+	"geo-notifications/internal/repository"
+	"geo-notifications/internal/tracing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReactivateIncident возвращает инцидент из корзины; активный инцидент
+// возвращается как есть.
+func (is *incidentService) ReactivateIncident(ctx context.Context, id int64) (_ *model.Incident, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.ReactivateIncident")
+	defer func() { tracing.End(span, err) }()
+
+	if id <= 0 {
+		return nil, NewValidationError(FieldError{Field: "id", Message: "must be positive"})
+	}
+
+	inc, changed, err := is.storage.Reactivate(ctx, auth.TenantFromContext(ctx), actor(ctx), id)
+	if err != nil {
+		return nil, is.storageError(ctx, err, id, "failed to reactivate incident")
+	}
+
+	if changed {
+		ev := *inc
+		is.publish(ctx, model.Event{Type: model.EventIncidentReactivated, Incident: &ev})
+	}
+	return inc, nil
+}
+
+// PurgeIncident удаляет инцидент из корзины насовсем.
+func (is *incidentService) PurgeIncident(ctx context.Context, id int64) (err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.PurgeIncident")
+	defer func() { tracing.End(span, err) }()
+
+	if id <= 0 {
+		return NewValidationError(FieldError{Field: "id", Message: "must be positive"})
+	}
+
+	inc, err := is.storage.Purge(ctx, auth.TenantFromContext(ctx), actor(ctx), id)
+	if err != nil {
+		return is.storageError(ctx, err, id, "failed to purge incident")
+	}
+
+	is.publish(ctx, model.Event{Type: model.EventIncidentPurged, Incident: inc})
+	return nil
+}
+
+func (is *incidentService) GetTrash(ctx context.Context, page, pageSize int) (_ []model.Incident, err error) {
+	ctx, span := tracer.Start(ctx, "IncidentService.GetTrash")
+	defer func() { tracing.End(span, err) }()
+
+	var v validator
+	v.check(page >= 1, "page", "must be positive")
+	v.check(pageSize >= 1, "page_size", "must be positive")
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	items, err := is.storage.GetTrash(ctx, auth.TenantFromContext(ctx), page, pageSize)
+	if err != nil {
+		is.log(ctx).WithError(err).Error("failed to list trash")
+		return nil, err
+	}
+	return items, nil
+}
+
+const (
+	trashPurgeInterval = time.Hour
+	trashPurgeBatch    = 500
+	// автор записей истории об автоматическом удалении
+	trashPurgeActor = "system:retention"
+)
+
+// TrashPurger удаляет из корзины инциденты, пролежавшие дольше retention.
+// Реплики могут работать параллельно: строки берутся с SKIP LOCKED.
+type TrashPurger struct {
+	storage   *repository.Storage
+	logger    *logrus.Logger
+	retention time.Duration
+}
+
+func NewTrashPurger(storage *repository.Storage, logger *logrus.Logger, retention time.Duration) *TrashPurger {
+	return &TrashPurger{
+		storage:   storage,
+		logger:    logger,
+		retention: retention,
+	}
+}
+
+func (p *TrashPurger) Run(ctx context.Context) {
+	if p.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purge(ctx context.Context) {
+	before := time.Now().Add(-p.retention)
+	for {
+		purged, err := p.storage.PurgeTrash(ctx, trashPurgeActor, before, trashPurgeBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				p.logger.WithError(err).Error("failed to purge trash")
+			}
+			return
+		}
+		if len(purged) > 0 {
+			p.logger.Infof("purged %d incidents from trash", len(purged))
+		}
+		for _, pi := range purged {
+			p.publish(ctx, pi)
+		}
+		if len(purged) < trashPurgeBatch {
+			return
+		}
+	}
+}
+
+// publish рассылает incident.purged. Тенант в ctx у пургера нет, поэтому
+// он берётся из удалённой строки.
+func (p *TrashPurger) publish(ctx context.Context, pi repository.PurgedIncident) {
+	ev := model.Event{
+		Type:       model.EventIncidentPurged,
+		Tenant:     pi.Tenant,
+		Incident:   pi.Incident,
+		OccurredAt: time.Now().UTC(),
+	}
+	if err := p.storage.PublishEvent(ctx, &ev); err != nil {
+		p.logger.WithError(err).WithFields(logrus.Fields{
+			"tenant":      pi.Tenant,
+			"incident_id": pi.Incident.ID,
+		}).Warn("failed to publish event")
+	}
+}
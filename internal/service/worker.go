@@ -4,25 +4,45 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"geo-notifications/internal/metrics"
 	"geo-notifications/internal/model"
 	"geo-notifications/internal/repository"
+	"geo-notifications/internal/tracing"
 	"net/http"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var workerTracer = tracing.Tracer("service/worker")
+
+// webhookTimeout ограничивает одну доставку целиком, включая чтение ответа:
+// зависший получатель не должен останавливать очередь.
+const webhookTimeout = 10 * time.Second
+
 type WebhookWorker struct {
 	storage    *repository.Storage
 	logger     *logrus.Logger
 	webhookURL string
+	client     *http.Client
+	lifecycle  *Lifecycle
 }
 
-func NewWebhookWorker(storage *repository.Storage, logger *logrus.Logger, webhookURL string) *WebhookWorker {
+func NewWebhookWorker(storage *repository.Storage, logger *logrus.Logger, webhookURL string, lifecycle *Lifecycle) *WebhookWorker {
 	return &WebhookWorker{
 		storage:    storage,
 		logger:     logger,
 		webhookURL: webhookURL,
+		lifecycle:  lifecycle,
+		// клиентский span и заголовок traceparent для получателя
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		},
 	}
 }
 
@@ -32,35 +52,102 @@ func (w *WebhookWorker) Run(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		default:
-			res, err := w.storage.BLPopWebhookTask(ctx, 5*time.Second, w.webhookURL)
+			// цикл не длиннее ожидания BLPOP (5s) плюс доставка (не дольше
+			// webhookTimeout), поэтому сердцебиение старше ~15s значит, что
+			// воркер завис. /readyz только показывает его возраст, решать
+			// по нему — дело мониторинга
+			w.lifecycle.Beat()
+
+			// у каждого тенанта своя очередь
+			keys, err := w.storage.WebhookQueueKeys(ctx)
 			if err != nil {
-				w.logger.WithError(err).Error("BLPop error")
-				continue
+				w.logger.WithError(err).Error("failed to list webhook queues")
 			}
-
-			var task model.WebhookPayload
-			if err := json.Unmarshal([]byte(res), &task); err != nil {
-				w.logger.WithError(err).Error("unmarshal webhook task error")
+			if len(keys) == 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+				}
 				continue
 			}
 
-			body, err := json.Marshal(task)
+			res, err := w.storage.BLPopWebhookTask(ctx, 5*time.Second, keys...)
 			if err != nil {
-				w.logger.WithError(err).Error("marshal webhook task for http request")
+				w.logger.WithError(err).Error("BLPop error")
 				continue
 			}
-			req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhookURL, bytes.NewReader(body))
-			if err != nil {
-				w.logger.WithError(err).Error("invalid request to webhookURL")
+			if res == "" {
 				continue
 			}
-			req.Header.Set("Content-Type", "application/json")
-			client := http.Client{}
-			_, err = client.Do(req)
-			if err != nil {
-				w.logger.WithError(err).Error("problem while sending reqeust to webhookURL")
+
+			var task model.WebhookTask
+			if err := json.Unmarshal([]byte(res), &task); err != nil {
+				w.logger.WithError(err).Error("unmarshal webhook task error")
 				continue
 			}
+			w.deliver(ctx, task)
 		}
 	}
 }
+
+// deliver отправляет вебхук и записывает итог. Span доставки начинает
+// свою трассу со ссылкой на трассу проверки локации: ожидание в очереди
+// видно по атрибуту webhook.queue_delay_ms, а traceparent получателю
+// ставит otelhttp.
+func (w *WebhookWorker) deliver(ctx context.Context, task model.WebhookTask) {
+	opts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindConsumer)}
+	// у задач из очереди до включения трассировки контекста нет
+	if origin := trace.SpanContextFromContext(tracing.Extract(ctx, task.TraceContext)); origin.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: origin}))
+	}
+	ctx, span := workerTracer.Start(ctx, "webhook.deliver", append(opts,
+		trace.WithAttributes(
+			attribute.String("tenant", task.Tenant),
+			attribute.Int64("user_id", task.UserID),
+			attribute.Int64Slice("incident_ids", task.LocationsIDS),
+			attribute.Int64("webhook.queue_delay_ms", time.Since(task.CheckedAt).Milliseconds()),
+		))...)
+	var deliveryErr error
+	defer func() { tracing.End(span, deliveryErr) }()
+
+	body, err := json.Marshal(task.WebhookPayload)
+	if err != nil {
+		w.logger.WithError(err).Error("marshal webhook task for http request")
+		deliveryErr = err
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		w.logger.WithError(err).Error("invalid request to webhookURL")
+		deliveryErr = err
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	statusCode, errText := 0, ""
+	start := time.Now()
+	resp, err := w.client.Do(req)
+	metrics.WebhookDeliveryDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		w.logger.WithError(err).Error("problem while sending reqeust to webhookURL")
+		deliveryErr, errText = err, err.Error()
+	} else {
+		statusCode = resp.StatusCode
+		resp.Body.Close()
+		if statusCode < 200 || statusCode >= 300 {
+			w.logger.WithField("status", statusCode).Warn("webhookURL responded with non-2xx status")
+			deliveryErr = fmt.Errorf("webhook responded with status %d", statusCode)
+		}
+	}
+
+	if deliveryErr == nil {
+		metrics.WebhookDeliveries.WithLabelValues("success").Inc()
+	} else {
+		metrics.WebhookDeliveries.WithLabelValues("failure").Inc()
+	}
+
+	// итог нужен для доли успешных доставок в статистике инцидента
+	if err := w.storage.RecordWebhookDelivery(ctx, task.Tenant, task.LocationsIDS, statusCode, errText); err != nil {
+		w.logger.WithError(err).Error("failed to record webhook delivery")
+	}
+}
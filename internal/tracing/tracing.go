@@ -0,0 +1,87 @@
+// Package tracing настраивает OpenTelemetry. Без OTLP endpoint остаётся
+// no-op провайдер: спаны не пишутся, но контекст трассировки всё равно
+// пробрасывается (traceparent входящих запросов уходит дальше в вебхуки).
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "geo-notifications"
+
+// Setup ставит W3C Trace Context propagator и, если enabled, экспорт спанов
+// по OTLP/gRPC. Адрес и прочие параметры экспортёра берутся из стандартных
+// переменных OTEL_EXPORTER_OTLP_*, имя сервиса — из OTEL_SERVICE_NAME.
+// shutdown дописывает накопленные спаны.
+func Setup(ctx context.Context, enabled bool) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	if !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer возвращает трассировщик пакета из глобального провайдера, поэтому
+// его можно брать до Setup.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(serviceName + "/" + name)
+}
+
+// End завершает span, отмечая ошибку, если она есть.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject кладёт контекст трассировки ctx в carrier — для задач в очереди.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract восстанавливает контекст трассировки, сохранённый Inject.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// Traced сообщает, идёт ли ctx внутри трассы. Базу и Redis трассируем
+// только так: фоновые опросы (BLPOP воркера, метрики) не плодят трасс.
+func Traced(ctx context.Context) bool {
+	return trace.SpanContextFromContext(ctx).IsValid()
+}